@@ -9,6 +9,7 @@ import (
 	"syscall"
 	"time"
 
+	"DORAPollCredit/internal/bootstrap"
 	"DORAPollCredit/internal/chain"
 	"DORAPollCredit/internal/config"
 	"DORAPollCredit/internal/db"
@@ -33,32 +34,40 @@ func main() {
 
 	st := store.New(pool)
 	pricingSvc := pricing.Service{FixedCreditPerDora: cfg.Pricing.FixedCreditPerDora}
-	deriver := chain.AddressDeriver{XPub: cfg.Wallet.XPub, Prefix: cfg.Chain.Bech32Prefix}
-	rpcEndpoints := cfg.Chain.RPCEndpoints
-	if len(rpcEndpoints) == 0 {
-		log.Fatalf("rpc_endpoints is empty")
+	for _, t := range cfg.Pricing.Tiers {
+		pricingSvc.Tiers = append(pricingSvc.Tiers, pricing.Tier{MinCredit: t.MinCredit, DiscountBps: t.DiscountBps})
 	}
-	var rpc chain.Client
-	if len(rpcEndpoints) > 1 {
-		client, err := chain.NewMultiRPCClient(rpcEndpoints, cfg.Worker.RPCFailoverThreshold)
-		if err != nil {
-			log.Fatalf("rpc client init failed: %v", err)
+	if cfg.Pricing.Mode == "fiat" {
+		f := cfg.Pricing.Fiat
+		pricingSvc.Fiat = &pricing.FiatConfig{
+			Currency:    f.Currency,
+			CreditPrice: f.CreditPrice,
+			Feed:        pricing.NewFeed(f.FeedURL, f.FeedPath, f.DoraPrice, time.Duration(f.CacheSeconds)*time.Second),
 		}
-		rpc = client
-	} else {
-		rpc = chain.NewRPCClient(rpcEndpoints[0])
+	}
+	deriver := chain.AddressDeriver{XPub: cfg.Wallet.XPub, Prefix: cfg.Chain.Bech32Prefix}
+	rpc, verify, err := bootstrap.Chain(ctx, cfg)
+	if err != nil {
+		log.Fatalf("chain init failed: %v", err)
 	}
 	orderSvc := &services.OrderService{
-		Store:     st,
-		Deriver:   deriver,
-		Pricing:   pricingSvc,
-		MinCredit: cfg.Orders.MinCredit,
-		TTL:       time.Duration(cfg.Orders.TTLMinutes) * time.Minute,
-		Denom:     cfg.Chain.Denom,
-		Decimals:  cfg.Chain.Decimals,
+		Store:       st,
+		Deriver:     deriver,
+		Pricing:     pricingSvc,
+		MinCredit:   cfg.Orders.MinCredit,
+		TTL:         time.Duration(cfg.Orders.TTLMinutes) * time.Minute,
+		Denom:       cfg.Chain.Denom,
+		Decimals:    cfg.Chain.Decimals,
+		QuoteSecret: cfg.Orders.QuoteSecret,
+		QuoteTTL:    time.Duration(cfg.Orders.QuoteTTLSeconds) * time.Second,
+		Verify:      verify,
+		Chain:       rpc,
 	}
 
-	h := internalhttp.NewHandler(orderSvc, rpc)
+	productSvc := &services.ProductService{Store: st}
+	promoSvc := &services.PromoService{Store: st}
+
+	h := internalhttp.NewHandler(orderSvc, productSvc, promoSvc, rpc)
 	srv := internalhttp.NewServer(h)
 
 	httpServer := &http.Server{
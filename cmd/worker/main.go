@@ -5,9 +5,11 @@ import (
 	"log"
 	"time"
 
+	"DORAPollCredit/internal/bootstrap"
 	"DORAPollCredit/internal/chain"
 	"DORAPollCredit/internal/config"
 	"DORAPollCredit/internal/db"
+	"DORAPollCredit/internal/pricing"
 	"DORAPollCredit/internal/store"
 	"DORAPollCredit/internal/worker"
 )
@@ -33,24 +35,22 @@ func main() {
 	defer pool.Close()
 
 	st := store.New(pool)
-	rpcEndpoints := cfg.Chain.RPCEndpoints
-	if len(rpcEndpoints) == 0 {
-		log.Fatalf("rpc_endpoints is empty")
-	}
-
-	var rpc chain.Client
-	if len(rpcEndpoints) > 1 {
-		client, err := chain.NewMultiRPCClient(rpcEndpoints, cfg.Worker.RPCFailoverThreshold)
-		if err != nil {
-			log.Fatalf("rpc client init failed: %v", err)
+	pricingSvc := pricing.Service{FixedCreditPerDora: cfg.Pricing.FixedCreditPerDora}
+	if cfg.Pricing.Mode == "fiat" {
+		f := cfg.Pricing.Fiat
+		pricingSvc.Fiat = &pricing.FiatConfig{
+			Currency:    f.Currency,
+			CreditPrice: f.CreditPrice,
+			Feed:        pricing.NewFeed(f.FeedURL, f.FeedPath, f.DoraPrice, time.Duration(f.CacheSeconds)*time.Second),
 		}
-		rpc = client
-	} else {
-		rpc = chain.NewRPCClient(rpcEndpoints[0])
+	}
+	rpc, verify, err := bootstrap.Chain(ctx, cfg)
+	if err != nil {
+		log.Fatalf("chain init failed: %v", err)
 	}
 	wsEndpoints := cfg.Chain.WSEndpoints
 	if len(wsEndpoints) == 0 {
-		for _, rpcEndpoint := range rpcEndpoints {
+		for _, rpcEndpoint := range cfg.Chain.RPCEndpoints {
 			if ws := chain.DefaultWSEndpoint(rpcEndpoint); ws != "" {
 				wsEndpoints = append(wsEndpoints, ws)
 			}
@@ -67,15 +67,35 @@ func main() {
 		Decimals:            cfg.Chain.Decimals,
 		ConfirmDepth:        int64(cfg.Chain.ConfirmDepth),
 		StartHeight:         cfg.Worker.StartHeight,
-		RewindBlocks:        cfg.Worker.RewindBlocks,
 		MaxBlocksPerTick:    cfg.Worker.MaxBlocksPerTick,
 		PerPage:             cfg.Worker.PerPage,
 		Interval:            time.Duration(max64(cfg.Worker.IntervalSeconds, 1)) * time.Second,
+		LateWatch:           time.Duration(cfg.Worker.LateWatchDays) * 24 * time.Hour,
+		OrphanWatch:         time.Duration(cfg.Worker.OrphanWatchDays) * 24 * time.Hour,
+		LateScanInterval:    time.Duration(cfg.Worker.LateScanIntervalSec) * time.Second,
 		WSEndpoints:         wsEndpoints,
 		WSBackfillBlocks:    cfg.Worker.WSBackfillBlocks,
+		WSBackfillMax:       cfg.Worker.WSBackfillMaxBlocks,
+		WSBackfillChunk:     cfg.Worker.WSBackfillChunkBlocks,
 		WSFailoverThreshold: cfg.Worker.WSFailoverThreshold,
+		WSRedundancy:        cfg.Worker.WSRedundancy,
+		WSMaxSubscriptions:  cfg.Worker.WSMaxSubscriptions,
+		WSSubscriptionSync:  time.Duration(cfg.Worker.WSSubscriptionSyncSec) * time.Second,
+		WSPingInterval:      time.Duration(cfg.Worker.WSPingIntervalSec) * time.Second,
+		WSReadTimeout:       time.Duration(cfg.Worker.WSReadTimeoutSec) * time.Second,
+		Pricing:             pricingSvc,
+		ScanMode:            cfg.Worker.ScanMode,
+		Verify:              verify,
+		AttrEncoding:        chain.AttrEncoding(cfg.Chain.AttrEncoding),
+		Deriver:             chain.AddressDeriver{XPub: cfg.Wallet.XPub, Prefix: cfg.Chain.Bech32Prefix},
+		AddressLookahead:    cfg.Worker.AddressLookahead,
+		LookaheadScanBudget: cfg.Worker.LookaheadScanBudget,
+	}
+
+	if w.ScanMode == "" {
+		w.ScanMode = worker.ScanModeTxSearch
 	}
 
-	log.Printf("worker started (rpc=%v)", rpcEndpoints)
+	log.Printf("worker started (rpc=%v scan_mode=%s)", cfg.Chain.RPCEndpoints, w.ScanMode)
 	w.Run(ctx)
 }
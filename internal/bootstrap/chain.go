@@ -0,0 +1,89 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"DORAPollCredit/internal/chain"
+	"DORAPollCredit/internal/config"
+	"DORAPollCredit/internal/payments"
+)
+
+// Chain builds the chain client and the payment verification shared by the
+// api and the worker. With several endpoints it also starts the health
+// probes, which stop with ctx.
+func Chain(ctx context.Context, cfg *config.Config) (chain.Client, payments.Verification, error) {
+	verify := payments.Verification{Denom: cfg.Chain.Denom}
+	if len(cfg.Chain.RPCEndpoints) == 0 && len(cfg.Chain.LCDEndpoints) == 0 {
+		return nil, verify, errors.New("rpc_endpoints is empty")
+	}
+	p := policy(cfg)
+	rpc, err := chain.NewClient(cfg.Chain.RPCEndpoints, cfg.Chain.LCDEndpoints, cfg.Worker.RPCFailoverThreshold, p)
+	if err != nil {
+		return nil, verify, fmt.Errorf("rpc client init failed: %w", err)
+	}
+	multi, isMulti := rpc.(*chain.MultiRPCClient)
+
+	if cfg.Chain.QuorumSize > 1 {
+		if !isMulti {
+			log.Printf("quorum_size=%d ignored: only one endpoint configured", cfg.Chain.QuorumSize)
+		} else if cfg.Chain.QuorumSize > multi.Len() {
+			return nil, verify, fmt.Errorf("quorum_size=%d exceeds the %d configured endpoints", cfg.Chain.QuorumSize, multi.Len())
+		} else {
+			verify.Quorum = &payments.Quorum{
+				Verifier:  multi,
+				MinAmount: cfg.Chain.QuorumMinAmount,
+				Size:      cfg.Chain.QuorumSize,
+				Denom:     cfg.Chain.Denom,
+			}
+		}
+	}
+	if lc := cfg.Chain.LightClient; lc.Enabled {
+		// Proof fetches share the executor of the primary when it is also a
+		// configured RPC endpoint.
+		primary := chain.RPCClientFor(rpc, lc.Primary)
+		if primary == nil {
+			primary = chain.NewRPCClient(lc.Primary, p)
+		}
+		prover, err := chain.NewProofVerifier(ctx, chain.LightClientConfig{
+			ChainID:     cfg.Chain.ChainID,
+			Primary:     lc.Primary,
+			Witnesses:   lc.Witnesses,
+			TrustHeight: lc.TrustHeight,
+			TrustHash:   lc.TrustHash,
+			TrustPeriod: time.Duration(lc.TrustPeriodHours) * time.Hour,
+		}, primary)
+		if err != nil {
+			return nil, verify, fmt.Errorf("light client init failed: %w", err)
+		}
+		verify.Prover = prover
+	}
+
+	if isMulti {
+		go multi.RunHealthProbes(ctx, time.Duration(cfg.Worker.RPCHealthIntervalSec)*time.Second, cfg.Worker.RPCMaxLagBlocks)
+	}
+	return rpc, verify, nil
+}
+
+func policy(cfg *config.Config) chain.Policy {
+	rp := cfg.Chain.RPCPolicy
+	p := chain.Policy{
+		Timeout:          time.Duration(rp.TimeoutMs) * time.Millisecond,
+		MethodTimeouts:   map[string]time.Duration{},
+		MaxRetries:       rp.MaxRetries,
+		BaseBackoff:      time.Duration(rp.BackoffBaseMs) * time.Millisecond,
+		MaxBackoff:       time.Duration(rp.BackoffMaxMs) * time.Millisecond,
+		BreakerThreshold: rp.BreakerThreshold,
+		BreakerCooldown:  time.Duration(rp.BreakerCooldownSeconds) * time.Second,
+		RateLimit:        rp.RateLimitRPS,
+		Burst:            rp.RateBurst,
+		AttrEncoding:     chain.AttrEncoding(cfg.Chain.AttrEncoding),
+	}
+	for method, ms := range rp.MethodTimeoutsMs {
+		p.MethodTimeouts[method] = time.Duration(ms) * time.Millisecond
+	}
+	return p
+}
@@ -0,0 +1,69 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type Block struct {
+	Height   int64
+	Time     time.Time
+	TxHashes []string
+}
+
+// BlockResults is /block_results normalised across CometBFT versions.
+// 0.34 and 0.37 report BeginBlockEvents and EndBlockEvents; 0.38 replaces
+// both with FinalizeBlockEvents, tagged with a "mode" attribute by the SDK.
+type BlockResults struct {
+	Height              int64
+	TxResults           []TxResult
+	BeginBlockEvents    []Event
+	EndBlockEvents      []Event
+	FinalizeBlockEvents []Event
+}
+
+type TxResult struct {
+	Code      int
+	Codespace string
+	GasWanted int64
+	GasUsed   int64
+	Events    []Event
+}
+
+// BlockEvents returns the events emitted outside any tx, whichever fields the
+// node's version populated.
+func (r *BlockResults) BlockEvents() []Event {
+	out := make([]Event, 0, len(r.BeginBlockEvents)+len(r.EndBlockEvents)+len(r.FinalizeBlockEvents))
+	out = append(out, r.BeginBlockEvents...)
+	out = append(out, r.EndBlockEvents...)
+	return append(out, r.FinalizeBlockEvents...)
+}
+
+// BlockTxs joins /block and /block_results for height into Txs stamped with
+// the block time. The block-level events are returned alongside.
+func BlockTxs(ctx context.Context, c Client, height int64) ([]Tx, []Event, error) {
+	block, err := c.Block(ctx, height)
+	if err != nil {
+		return nil, nil, err
+	}
+	results, err := c.BlockResults(ctx, height)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(results.TxResults) != len(block.TxHashes) {
+		return nil, nil, fmt.Errorf("block %d has %d txs but %d results", height, len(block.TxHashes), len(results.TxResults))
+	}
+
+	txs := make([]Tx, 0, len(block.TxHashes))
+	for i, hash := range block.TxHashes {
+		txs = append(txs, Tx{
+			Hash:      hash,
+			Height:    height,
+			Code:      results.TxResults[i].Code,
+			Events:    results.TxResults[i].Events,
+			Timestamp: block.Time,
+		})
+	}
+	return txs, results.BlockEvents(), nil
+}
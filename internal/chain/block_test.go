@@ -0,0 +1,97 @@
+package chain
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	blockTestRecipient = "dora1qyqszqgpqyqszqgpqyqszqgpqyqszqgpjnp7du"
+	blockTestDistr     = "dora1jv65s3grqf6v6jl3dp4t6c9t9rk99cd8jgu2yp"
+)
+
+func TestRPCBlockResultsVersions(t *testing.T) {
+	tests := []struct {
+		version       string
+		height        int64
+		begin, end    int
+		finalize      int
+		blockEvents   int
+		blockReceiver string
+	}{
+		{version: "v0.34", height: 11450790, begin: 2, end: 0, finalize: 0, blockEvents: 2},
+		{version: "v0.37", height: 11450800, begin: 2, end: 1, finalize: 0, blockEvents: 3, blockReceiver: blockTestRecipient},
+		{version: "v0.38", height: 11450810, begin: 0, end: 0, finalize: 2, blockEvents: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			dir := filepath.Join("testdata", "block_results")
+			status, err := os.ReadFile(filepath.Join(dir, "status_"+tt.version+".json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			results, err := os.ReadFile(filepath.Join(dir, tt.version+".json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/status":
+					_, _ = w.Write(status)
+				case "/block_results":
+					_, _ = w.Write(results)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer srv.Close()
+
+			res, err := NewRPCClient(srv.URL, DefaultPolicy()).BlockResults(context.Background(), tt.height)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Height != tt.height {
+				t.Errorf("height = %d, want %d", res.Height, tt.height)
+			}
+			if len(res.BeginBlockEvents) != tt.begin || len(res.EndBlockEvents) != tt.end || len(res.FinalizeBlockEvents) != tt.finalize {
+				t.Errorf("begin/end/finalize = %d/%d/%d, want %d/%d/%d",
+					len(res.BeginBlockEvents), len(res.EndBlockEvents), len(res.FinalizeBlockEvents),
+					tt.begin, tt.end, tt.finalize)
+			}
+
+			if len(res.TxResults) != 2 {
+				t.Fatalf("tx results = %d, want 2", len(res.TxResults))
+			}
+			ok, failed := res.TxResults[0], res.TxResults[1]
+			if ok.Code != 0 || ok.GasUsed != 76543 || ok.GasWanted != 200000 {
+				t.Errorf("tx 0 = code %d gas %d/%d", ok.Code, ok.GasUsed, ok.GasWanted)
+			}
+			if !hasAttr(ok.Events, "transfer", "recipient", blockTestRecipient) ||
+				!hasAttr(ok.Events, "transfer", "amount", "1500000peaka") {
+				t.Errorf("tx 0 transfer not decoded: %+v", ok.Events)
+			}
+			if failed.Code != 5 || failed.Codespace != "sdk" {
+				t.Errorf("tx 1 = code %d codespace %q, want 5 sdk", failed.Code, failed.Codespace)
+			}
+
+			events := res.BlockEvents()
+			if len(events) != tt.blockEvents {
+				t.Fatalf("block events = %d, want %d", len(events), tt.blockEvents)
+			}
+			if !hasAttr(events, "transfer", "recipient", blockTestDistr) ||
+				!hasAttr(events, "transfer", "amount", "88123peaka") {
+				t.Errorf("block-level transfer not decoded: %+v", events)
+			}
+			if tt.blockReceiver != "" && !hasAttr(events, "coin_received", "receiver", tt.blockReceiver) {
+				t.Errorf("end-block coin_received missing: %+v", events)
+			}
+			if tt.finalize > 0 && !hasAttr(events, "transfer", "mode", "BeginBlock") {
+				t.Errorf("finalize_block_events lost the mode attribute: %+v", events)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package chain
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultBlockTimeCacheSize holds roughly a day of 5s blocks.
+	defaultBlockTimeCacheSize = 20000
+	// maxBatchSize bounds one JSON-RPC batch; nodes reject very large ones.
+	maxBatchSize = 50
+
+	rpcMethodNotFound = -32601
+)
+
+// blockTimeCache is an LRU of height -> block time. Block times are final, so
+// entries never need invalidation.
+type blockTimeCache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	items map[int64]*list.Element
+}
+
+type blockTimeEntry struct {
+	height int64
+	time   time.Time
+}
+
+func newBlockTimeCache(size int) *blockTimeCache {
+	if size <= 0 {
+		size = defaultBlockTimeCacheSize
+	}
+	return &blockTimeCache{size: size, order: list.New(), items: map[int64]*list.Element{}}
+}
+
+func (c *blockTimeCache) get(height int64) (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[height]
+	if !ok {
+		return time.Time{}, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(blockTimeEntry).time, true
+}
+
+func (c *blockTimeCache) put(height int64, t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[height]; ok {
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[height] = c.order.PushFront(blockTimeEntry{height: height, time: t})
+	for c.order.Len() > c.size {
+		last := c.order.Back()
+		c.order.Remove(last)
+		delete(c.items, last.Value.(blockTimeEntry).height)
+	}
+}
+
+// BlockTimes returns the block time of every height, serving cached heights
+// locally and fetching the rest with JSON-RPC batch requests.
+func (c *RPCClient) BlockTimes(ctx context.Context, heights []int64) (map[int64]time.Time, error) {
+	out := make(map[int64]time.Time, len(heights))
+	var missing []int64
+	for _, h := range uniqueHeights(heights) {
+		if t, ok := c.times.get(h); ok {
+			out[h] = t
+			continue
+		}
+		missing = append(missing, h)
+	}
+	for len(missing) > 0 {
+		n := min(len(missing), maxBatchSize)
+		times, err := c.fetchBlockTimes(ctx, missing[:n])
+		if err != nil {
+			return nil, err
+		}
+		for h, t := range times {
+			c.times.put(h, t)
+			out[h] = t
+		}
+		missing = missing[n:]
+	}
+	return out, nil
+}
+
+// fetchBlockTimes asks for headers, falling back to full blocks on nodes that
+// predate the header method.
+func (c *RPCClient) fetchBlockTimes(ctx context.Context, heights []int64) (map[int64]time.Time, error) {
+	method := "header"
+	if c.noHeaderMethod() {
+		method = "block"
+	}
+	times, err := c.batchHeaderTimes(ctx, method, heights)
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) && rpcErr.Code == rpcMethodNotFound && method == "header" {
+		c.setNoHeaderMethod()
+		return c.batchHeaderTimes(ctx, "block", heights)
+	}
+	return times, err
+}
+
+func (c *RPCClient) batchHeaderTimes(ctx context.Context, method string, heights []int64) (map[int64]time.Time, error) {
+	reqs := make([]jsonRPCRequest, 0, len(heights))
+	for i, h := range heights {
+		reqs = append(reqs, jsonRPCRequest{
+			JSONRPC: "2.0",
+			ID:      i,
+			Method:  method,
+			Params:  map[string]string{"height": strconv.FormatInt(h, 10)},
+		})
+	}
+	body, err := json.Marshal(reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	var resps []jsonRPCResponse
+	err = c.exec.Do(ctx, method, func(ctx context.Context) error {
+		return postJSON(ctx, c.client, c.baseURL, body, &resps)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[int64]time.Time, len(heights))
+	for _, resp := range resps {
+		if resp.Error != nil {
+			return nil, resp.Error
+		}
+		if resp.ID < 0 || resp.ID >= len(heights) {
+			return nil, fmt.Errorf("batch response has unknown id %d", resp.ID)
+		}
+		var result struct {
+			Header *blockHeader `json:"header"`
+			Block  struct {
+				Header blockHeader `json:"header"`
+			} `json:"block"`
+		}
+		if err := json.Unmarshal(resp.Result, &result); err != nil {
+			return nil, err
+		}
+		header := result.Header
+		if header == nil {
+			header = &result.Block.Header
+		}
+		t, err := time.Parse(time.RFC3339, header.Time)
+		if err != nil {
+			return nil, err
+		}
+		out[heights[resp.ID]] = t
+	}
+	if len(out) != len(heights) {
+		return nil, fmt.Errorf("batch returned %d of %d block times", len(out), len(heights))
+	}
+	return out, nil
+}
+
+func (c *RPCClient) noHeaderMethod() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.legacyHeader
+}
+
+func (c *RPCClient) setNoHeaderMethod() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.legacyHeader = true
+}
+
+// FillBlockTimes sets Timestamp on txs that lack one, using one batched
+// lookup for all of their heights.
+func FillBlockTimes(ctx context.Context, c Client, txs []Tx) error {
+	var heights []int64
+	for _, tx := range txs {
+		if tx.Timestamp.IsZero() {
+			heights = append(heights, tx.Height)
+		}
+	}
+	if len(heights) == 0 {
+		return nil
+	}
+	times, err := c.BlockTimes(ctx, heights)
+	if err != nil {
+		return err
+	}
+	for i := range txs {
+		if txs[i].Timestamp.IsZero() {
+			txs[i].Timestamp = times[txs[i].Height]
+		}
+	}
+	return nil
+}
+
+func uniqueHeights(heights []int64) []int64 {
+	seen := make(map[int64]struct{}, len(heights))
+	out := make([]int64, 0, len(heights))
+	for _, h := range heights {
+		if _, ok := seen[h]; ok {
+			continue
+		}
+		seen[h] = struct{}{}
+		out = append(out, h)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+func postJSON(ctx context.Context, client *http.Client, endpoint string, body []byte, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return &HTTPStatusError{
+			StatusCode: resp.StatusCode,
+			Body:       strings.TrimSpace(string(b)),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+type jsonRPCRequest struct {
+	JSONRPC string            `json:"jsonrpc"`
+	ID      int               `json:"id"`
+	Method  string            `json:"method"`
+	Params  map[string]string `json:"params"`
+}
+
+type jsonRPCResponse struct {
+	ID     int             `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+}
+
+type blockHeader struct {
+	Height string `json:"height"`
+	Time   string `json:"time"`
+}
+
+// RPCError is a JSON-RPC error object.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    string `json:"data"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s %s", e.Code, e.Message, e.Data)
+}
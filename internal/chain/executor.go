@@ -0,0 +1,273 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without contacting the endpoint while its
+// circuit breaker is open.
+var ErrCircuitOpen = errors.New("rpc circuit open")
+
+// Policy controls how requests to a single endpoint are timed out, retried,
+// broken and rate limited, and how their events are decoded. Zero fields fall back to DefaultPolicy.
+type Policy struct {
+	// Timeout bounds one attempt; MethodTimeouts overrides it per RPC method
+	// (status, tx_search, tx, block, block_results).
+	Timeout        time.Duration
+	MethodTimeouts map[string]time.Duration
+	// MaxRetries is the number of extra attempts for retryable errors.
+	MaxRetries  int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// BreakerThreshold consecutive failures open the circuit for
+	// BreakerCooldown, after which one trial request at a time is let through.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+	// RateLimit is the sustained requests per second; 0 disables limiting.
+	RateLimit float64
+	Burst     int
+	// AttrEncoding forces how event attributes are decoded; the default
+	// detects it from the node version.
+	AttrEncoding AttrEncoding
+}
+
+func DefaultPolicy() Policy {
+	return Policy{
+		Timeout:          10 * time.Second,
+		MaxRetries:       2,
+		BaseBackoff:      200 * time.Millisecond,
+		MaxBackoff:       5 * time.Second,
+		BreakerThreshold: 5,
+		BreakerCooldown:  30 * time.Second,
+	}
+}
+
+func (p Policy) withDefaults() Policy {
+	d := DefaultPolicy()
+	if p.Timeout <= 0 {
+		p.Timeout = d.Timeout
+	}
+	if p.MaxRetries < 0 {
+		p.MaxRetries = 0
+	}
+	if p.BaseBackoff <= 0 {
+		p.BaseBackoff = d.BaseBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = d.MaxBackoff
+	}
+	if p.BreakerThreshold <= 0 {
+		p.BreakerThreshold = d.BreakerThreshold
+	}
+	if p.BreakerCooldown <= 0 {
+		p.BreakerCooldown = d.BreakerCooldown
+	}
+	if p.RateLimit > 0 && p.Burst <= 0 {
+		p.Burst = 1
+	}
+	return p
+}
+
+// Executor runs requests against one endpoint under a Policy. Each endpoint
+// owns its own executor so breakers and rate limits are tracked separately.
+type Executor struct {
+	policy Policy
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	// trial is set while the single half-open request is in flight.
+	trial    bool
+	tokens   float64
+	refilled time.Time
+}
+
+func NewExecutor(p Policy) *Executor {
+	p = p.withDefaults()
+	return &Executor{policy: p, tokens: float64(p.Burst), refilled: time.Now()}
+}
+
+// Do calls fn with a per-attempt timeout, retrying retryable errors with
+// jittered exponential backoff. Only the final error is returned.
+func (e *Executor) Do(ctx context.Context, method string, fn func(ctx context.Context) error) error {
+	var err error
+	for attempt := 0; attempt <= e.policy.MaxRetries; attempt++ {
+		if attempt > 0 {
+			if werr := sleepCtx(ctx, e.backoff(attempt, err)); werr != nil {
+				return err
+			}
+		}
+		trial, ok := e.acquire()
+		if !ok {
+			return ErrCircuitOpen
+		}
+		if werr := e.wait(ctx); werr != nil {
+			e.release(trial)
+			return werr
+		}
+
+		attemptCtx, cancel := context.WithTimeout(ctx, e.timeout(method))
+		err = fn(attemptCtx)
+		cancel()
+		if err == nil {
+			e.record(true, trial)
+			return nil
+		}
+		// Client errors other than 429 say nothing about endpoint health.
+		if ctx.Err() != nil || !isRetryable(err) {
+			e.release(trial)
+			return err
+		}
+		e.record(false, trial)
+	}
+	return err
+}
+
+// Available reports whether the breaker would currently let a request through.
+// Once the cooldown passes the circuit is half-open and lets one trial request
+// through; its result closes or re-opens the circuit.
+func (e *Executor) Available() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.openUntil.IsZero() || (!time.Now().Before(e.openUntil) && !e.trial)
+}
+
+// acquire claims a request slot: always while the circuit is closed, and only
+// for the single trial request while it is half-open. trial reports whether
+// the caller holds that trial.
+func (e *Executor) acquire() (trial, ok bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.openUntil.IsZero() {
+		return false, true
+	}
+	if time.Now().Before(e.openUntil) || e.trial {
+		return false, false
+	}
+	e.trial = true
+	return true, true
+}
+
+// release gives up a trial that ended without a verdict on endpoint health.
+func (e *Executor) release(trial bool) {
+	if !trial {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.trial = false
+}
+
+func (e *Executor) timeout(method string) time.Duration {
+	if d, ok := e.policy.MethodTimeouts[method]; ok && d > 0 {
+		return d
+	}
+	return e.policy.Timeout
+}
+
+func (e *Executor) backoff(attempt int, err error) time.Duration {
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		return min(statusErr.RetryAfter, e.policy.MaxBackoff)
+	}
+	d := e.policy.BaseBackoff << (attempt - 1)
+	if d <= 0 || d > e.policy.MaxBackoff {
+		d = e.policy.MaxBackoff
+	}
+	// Full jitter keeps retries from several workers from synchronising.
+	return time.Duration(rand.Int63n(int64(d)) + 1)
+}
+
+func (e *Executor) record(ok, trial bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if trial {
+		e.trial = false
+	}
+	if ok {
+		e.failures = 0
+		e.openUntil = time.Time{}
+		return
+	}
+	e.failures++
+	if e.failures >= e.policy.BreakerThreshold {
+		e.openUntil = time.Now().Add(e.policy.BreakerCooldown)
+		e.failures = e.policy.BreakerThreshold - 1
+	}
+}
+
+// wait takes one token from the bucket, sleeping until one is available.
+func (e *Executor) wait(ctx context.Context) error {
+	if e.policy.RateLimit <= 0 {
+		return nil
+	}
+	for {
+		e.mu.Lock()
+		now := time.Now()
+		e.tokens += now.Sub(e.refilled).Seconds() * e.policy.RateLimit
+		if burst := float64(e.policy.Burst); e.tokens > burst {
+			e.tokens = burst
+		}
+		e.refilled = now
+		if e.tokens >= 1 {
+			e.tokens--
+			e.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((1 - e.tokens) / e.policy.RateLimit * float64(time.Second))
+		e.mu.Unlock()
+		if err := sleepCtx(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+func isRetryable(err error) bool {
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+	return isTransportError(err)
+}
+
+func isTransportError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
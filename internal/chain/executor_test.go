@@ -0,0 +1,63 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestExecutorHalfOpenAllowsOneTrial(t *testing.T) {
+	p := DefaultPolicy()
+	p.MaxRetries = 0
+	p.BreakerThreshold = 1
+	p.BreakerCooldown = 10 * time.Millisecond
+	e := NewExecutor(p)
+	ctx := context.Background()
+
+	failing := &HTTPStatusError{StatusCode: 503}
+	if err := e.Do(ctx, "status", func(context.Context) error { return failing }); !errors.Is(err, failing) {
+		t.Fatalf("first call = %v", err)
+	}
+	if err := e.Do(ctx, "status", func(context.Context) error { return nil }); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("call during cooldown = %v, want ErrCircuitOpen", err)
+	}
+	time.Sleep(2 * p.BreakerCooldown)
+
+	release := make(chan struct{})
+	var calls atomic.Int32
+	var rejected atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := e.Do(ctx, "status", func(context.Context) error {
+				calls.Add(1)
+				<-release
+				return nil
+			})
+			if errors.Is(err, ErrCircuitOpen) {
+				rejected.Add(1)
+			}
+		}()
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for rejected.Load() < 7 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if e.Available() {
+		t.Error("Available while the trial is in flight")
+	}
+	close(release)
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("half-open let %d requests through, want 1", got)
+	}
+	if !e.Available() {
+		t.Error("circuit still open after a successful trial")
+	}
+}
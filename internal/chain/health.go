@@ -0,0 +1,155 @@
+package chain
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+type NodeStatus struct {
+	LatestHeight int64
+	CatchingUp   bool
+}
+
+// StatusProber is implemented by clients that can report node sync status.
+type StatusProber interface {
+	Status(ctx context.Context) (*NodeStatus, error)
+}
+
+// EndpointHealth is the last probe result for one endpoint.
+type EndpointHealth struct {
+	Endpoint     string
+	Active       bool
+	Healthy      bool
+	LatestHeight int64
+	Lag          int64
+	CatchingUp   bool
+	NodeVersion  string
+	Latency      time.Duration
+	LastError    string
+	CheckedAt    time.Time
+}
+
+// RunHealthProbes probes every endpoint each interval, marks endpoints that
+// error, are catching up or lag the best height by more than maxLag as
+// unhealthy, and switches to the healthy endpoint with the lowest latency.
+func (m *MultiRPCClient) RunHealthProbes(ctx context.Context, interval time.Duration, maxLag int64) {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		m.probeAll(ctx, maxLag)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Health returns a snapshot of the last probe round.
+func (m *MultiRPCClient) Health() []EndpointHealth {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]EndpointHealth, len(m.clients))
+	for i, c := range m.clients {
+		if i < len(m.health) {
+			out[i] = m.health[i]
+		}
+		out[i].Endpoint = c.BaseURL()
+		out[i].Active = i == m.index
+	}
+	return out
+}
+
+func (m *MultiRPCClient) probeAll(ctx context.Context, maxLag int64) {
+	results := make([]EndpointHealth, len(m.clients))
+	var wg sync.WaitGroup
+	for i, c := range m.clients {
+		wg.Add(1)
+		go func(i int, c Client) {
+			defer wg.Done()
+			results[i] = probe(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	var best int64
+	for _, r := range results {
+		if r.LastError == "" && r.LatestHeight > best {
+			best = r.LatestHeight
+		}
+	}
+	pick := -1
+	for i := range results {
+		r := &results[i]
+		if r.LastError != "" {
+			continue
+		}
+		r.Lag = best - r.LatestHeight
+		r.Healthy = !r.CatchingUp && (maxLag <= 0 || r.Lag <= maxLag)
+		if r.Healthy && (pick < 0 || r.Latency < results[pick].Latency) {
+			pick = i
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.health = results
+	if pick < 0 || pick == m.index {
+		return
+	}
+	cur := results[m.index]
+	if !cur.Healthy || results[pick].Latency*2 < cur.Latency {
+		log.Printf("rpc switch %s -> %s (health probe)", m.clients[m.index].BaseURL(), m.clients[pick].BaseURL())
+		m.index = pick
+		m.failCount = 0
+	}
+}
+
+func probe(ctx context.Context, c Client) EndpointHealth {
+	h := EndpointHealth{Endpoint: c.BaseURL(), CheckedAt: time.Now().UTC()}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	if p, ok := c.(StatusProber); ok {
+		st, err := p.Status(ctx)
+		h.Latency = time.Since(start)
+		if err != nil {
+			h.LastError = err.Error()
+			return h
+		}
+		h.LatestHeight = st.LatestHeight
+		h.CatchingUp = st.CatchingUp
+		if v, ok := c.(interface{ NodeVersion() string }); ok {
+			h.NodeVersion = v.NodeVersion()
+		}
+		return h
+	}
+	height, err := c.LatestHeight(ctx)
+	h.Latency = time.Since(start)
+	if err != nil {
+		h.LastError = err.Error()
+		return h
+	}
+	h.LatestHeight = height
+	return h
+}
+
+// usable reports whether idx may be selected. Endpoints whose circuit breaker
+// is open are skipped; endpoints that have not been probed yet are assumed
+// usable.
+func (m *MultiRPCClient) usable(idx int) bool {
+	if b, ok := m.clients[idx].(interface{ Available() bool }); ok && !b.Available() {
+		return false
+	}
+	if idx >= len(m.health) || m.health[idx].CheckedAt.IsZero() {
+		return true
+	}
+	return m.health[idx].Healthy
+}
@@ -0,0 +1,348 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LCDClient implements Client on top of the Cosmos SDK REST gateway for
+// providers that do not expose CometBFT RPC.
+type LCDClient struct {
+	baseURL  string
+	client   *http.Client
+	exec     *Executor
+	times    *blockTimeCache
+	versions *versionDetector
+
+	mu     sync.Mutex
+	legacy bool
+}
+
+func NewLCDClient(baseURL string, policy Policy) *LCDClient {
+	return &LCDClient{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		client:   &http.Client{},
+		exec:     NewExecutor(policy),
+		times:    newBlockTimeCache(0),
+		versions: newVersionDetector(policy.AttrEncoding),
+	}
+}
+
+// Available is false while the endpoint's circuit breaker is open.
+func (c *LCDClient) Available() bool {
+	return c.exec.Available()
+}
+
+func (c *LCDClient) BaseURL() string {
+	return c.baseURL
+}
+
+func (c *LCDClient) LatestHeight(ctx context.Context) (int64, error) {
+	var resp lcdBlockResponse
+	if err := c.getJSON(ctx, "status", c.baseURL+"/cosmos/base/tendermint/v1beta1/blocks/latest", &resp); err != nil {
+		return 0, err
+	}
+	return parseInt64(resp.Block.Header.Height)
+}
+
+func (c *LCDClient) Status(ctx context.Context) (*NodeStatus, error) {
+	height, err := c.LatestHeight(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.attrEncoding(ctx); err != nil {
+		return nil, err
+	}
+	var syncing struct {
+		Syncing bool `json:"syncing"`
+	}
+	if err := c.getJSON(ctx, "status", c.baseURL+"/cosmos/base/tendermint/v1beta1/syncing", &syncing); err != nil {
+		return nil, err
+	}
+	return &NodeStatus{LatestHeight: height, CatchingUp: syncing.Syncing}, nil
+}
+
+// TxSearch uses the SDK 0.47+ query parameter and falls back to the legacy
+// events list once a gateway shows it does not support that parameter. The
+// legacy form only supports equality terms, so height bounds are dropped and
+// callers filter instead.
+func (c *LCDClient) TxSearch(ctx context.Context, query string, page, perPage int, order TxSearchOrder) (*TxSearchResult, error) {
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 {
+		perPage = 30
+	}
+	if perPage > defaultMaxPerPage {
+		perPage = defaultMaxPerPage
+	}
+
+	if !c.isLegacy() {
+		res, err := c.txSearch(ctx, query, page, perPage, order, false)
+		if err == nil {
+			return res, nil
+		}
+		if !queryUnsupported(err) {
+			return nil, err
+		}
+		c.setLegacy()
+	}
+	return c.txSearch(ctx, query, page, perPage, order, true)
+}
+
+// queryUnsupported reports whether err is a gateway rejecting the query
+// parameter itself: a route missing or not implemented, or a pre-0.47 SDK
+// complaining that no events were given because it ignored query. Any other
+// 400, e.g. a malformed query on a current SDK, is returned as is.
+func queryUnsupported(err error) bool {
+	var statusErr *HTTPStatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+	switch statusErr.StatusCode {
+	case http.StatusNotFound, http.StatusNotImplemented:
+		return true
+	case http.StatusBadRequest:
+		return strings.Contains(statusErr.Body, "must declare at least one event")
+	}
+	return false
+}
+
+func (c *LCDClient) txSearch(ctx context.Context, query string, page, perPage int, order TxSearchOrder, legacy bool) (*TxSearchResult, error) {
+	enc, err := c.attrEncoding(ctx)
+	if err != nil {
+		return nil, err
+	}
+	values := url.Values{}
+	if legacy {
+		for _, term := range strings.Split(query, " AND ") {
+			term = strings.TrimSpace(term)
+			if term == "" || strings.ContainsAny(term, "<>") {
+				continue
+			}
+			values.Add("events", term)
+		}
+		values.Set("pagination.offset", strconv.Itoa((page-1)*perPage))
+		values.Set("pagination.limit", strconv.Itoa(perPage))
+		values.Set("pagination.count_total", "true")
+	} else {
+		values.Set("query", query)
+		values.Set("page", strconv.Itoa(page))
+		values.Set("limit", strconv.Itoa(perPage))
+	}
+	if order == OrderDesc {
+		values.Set("order_by", "ORDER_BY_DESC")
+	} else {
+		values.Set("order_by", "ORDER_BY_ASC")
+	}
+
+	endpoint := c.baseURL + "/cosmos/tx/v1beta1/txs?" + values.Encode()
+	var resp lcdTxsResponse
+	if err := c.getJSON(ctx, "tx_search", endpoint, &resp); err != nil {
+		return nil, err
+	}
+
+	totalStr := resp.Total
+	if totalStr == "" || totalStr == "0" {
+		totalStr = resp.Pagination.Total
+	}
+	total, _ := strconv.ParseInt(totalStr, 10, 64)
+	result := &TxSearchResult{TotalCount: total, PerPage: perPage}
+	for _, tr := range resp.TxResponses {
+		tx, err := tr.toTx(enc)
+		if err != nil {
+			return nil, err
+		}
+		result.Txs = append(result.Txs, *tx)
+	}
+	if result.TotalCount < int64(len(result.Txs)) {
+		result.TotalCount = int64(len(result.Txs))
+	}
+	return result, nil
+}
+
+func (c *LCDClient) TxByHash(ctx context.Context, hash string) (*Tx, error) {
+	h := strings.TrimSpace(hash)
+	if h == "" {
+		return nil, errors.New("empty tx hash")
+	}
+	h = strings.TrimPrefix(strings.ToUpper(h), "0X")
+	enc, err := c.attrEncoding(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		TxResponse lcdTxResponse `json:"tx_response"`
+	}
+	if err := c.getJSON(ctx, "tx", c.baseURL+"/cosmos/tx/v1beta1/txs/"+h, &resp); err != nil {
+		return nil, err
+	}
+	return resp.TxResponse.toTx(enc)
+}
+
+func (c *LCDClient) BlockTime(ctx context.Context, height int64) (time.Time, error) {
+	if t, ok := c.times.get(height); ok {
+		return t, nil
+	}
+	block, err := c.Block(ctx, height)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return block.Time, nil
+}
+
+// BlockTimes has no batch form on the REST gateway; uncached heights are
+// fetched one by one.
+func (c *LCDClient) BlockTimes(ctx context.Context, heights []int64) (map[int64]time.Time, error) {
+	out := make(map[int64]time.Time, len(heights))
+	for _, h := range uniqueHeights(heights) {
+		t, err := c.BlockTime(ctx, h)
+		if err != nil {
+			return nil, err
+		}
+		out[h] = t
+	}
+	return out, nil
+}
+
+func (c *LCDClient) Block(ctx context.Context, height int64) (*Block, error) {
+	endpoint := c.baseURL + "/cosmos/base/tendermint/v1beta1/blocks/" + strconv.FormatInt(height, 10)
+	var resp lcdBlockResponse
+	if err := c.getJSON(ctx, "block", endpoint, &resp); err != nil {
+		return nil, err
+	}
+	h, err := parseInt64(resp.Block.Header.Height)
+	if err != nil {
+		return nil, err
+	}
+	t, err := time.Parse(time.RFC3339, resp.Block.Header.Time)
+	if err != nil {
+		return nil, err
+	}
+	c.times.put(h, t)
+	block := &Block{Height: h, Time: t}
+	for _, raw := range resp.Block.Data.Txs {
+		hash, err := hashFromTx(raw)
+		if err != nil {
+			return nil, err
+		}
+		block.TxHashes = append(block.TxHashes, hash)
+	}
+	return block, nil
+}
+
+// BlockResults has no REST equivalent; it is rebuilt from the txs indexed at
+// height, ordered like the block.
+func (c *LCDClient) BlockResults(ctx context.Context, height int64) (*BlockResults, error) {
+	block, err := c.Block(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+	out := &BlockResults{Height: height}
+	if len(block.TxHashes) == 0 {
+		return out, nil
+	}
+
+	byHash := make(map[string]Tx, len(block.TxHashes))
+	query := "tx.height=" + strconv.FormatInt(height, 10)
+	for page := 1; len(byHash) < len(block.TxHashes); page++ {
+		res, err := c.TxSearch(ctx, query, page, defaultMaxPerPage, OrderAsc)
+		if err != nil {
+			return nil, err
+		}
+		for _, tx := range res.Txs {
+			byHash[strings.ToUpper(tx.Hash)] = tx
+		}
+		if len(res.Txs) == 0 || int64(page*res.PerPage) >= res.TotalCount {
+			break
+		}
+	}
+
+	for _, hash := range block.TxHashes {
+		tx, ok := byHash[hash]
+		if !ok {
+			return nil, fmt.Errorf("lcd missing tx %s at height %d", hash, height)
+		}
+		out.TxResults = append(out.TxResults, TxResult{Code: tx.Code, Events: tx.Events})
+	}
+	return out, nil
+}
+
+func (c *LCDClient) getJSON(ctx context.Context, method, endpoint string, out any) error {
+	return c.exec.Do(ctx, method, func(ctx context.Context) error {
+		return getJSON(ctx, c.client, endpoint, out)
+	})
+}
+
+func (c *LCDClient) isLegacy() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.legacy
+}
+
+func (c *LCDClient) setLegacy() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.legacy = true
+}
+
+// LCD response types
+
+type lcdBlockResponse struct {
+	Block struct {
+		Header struct {
+			Height string `json:"height"`
+			Time   string `json:"time"`
+		} `json:"header"`
+		Data struct {
+			Txs []string `json:"txs"`
+		} `json:"data"`
+	} `json:"block"`
+}
+
+type lcdTxsResponse struct {
+	TxResponses []lcdTxResponse `json:"tx_responses"`
+	Pagination  struct {
+		Total string `json:"total"`
+	} `json:"pagination"`
+	Total string `json:"total"`
+}
+
+type lcdTxResponse struct {
+	Height    string     `json:"height"`
+	TxHash    string     `json:"txhash"`
+	Code      int        `json:"code"`
+	Timestamp string     `json:"timestamp"`
+	Events    []rpcEvent `json:"events"`
+	Logs      []struct {
+		Events []rpcEvent `json:"events"`
+	} `json:"logs"`
+}
+
+func (tr lcdTxResponse) toTx(enc AttrEncoding) (*Tx, error) {
+	height, err := parseInt64(tr.Height)
+	if err != nil {
+		return nil, err
+	}
+	timestamp, _ := time.Parse(time.RFC3339, tr.Timestamp)
+	events := tr.Events
+	if len(events) == 0 {
+		for _, l := range tr.Logs {
+			events = append(events, l.Events...)
+		}
+	}
+	return &Tx{
+		Hash:      strings.ToUpper(tr.TxHash),
+		Height:    height,
+		Code:      tr.Code,
+		Events:    decodeEvents(events, enc),
+		Timestamp: timestamp,
+	}, nil
+}
@@ -0,0 +1,178 @@
+package chain
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+const lcdTestRecipient = "dora1qyqszqgpqyqszqgpqyqszqgpqyqszqgpjnp7du"
+
+func lcdFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("testdata", "lcd", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// lcdTestServer serves node_info and answers /cosmos/tx/v1beta1/txs with
+// txs(r), counting the tx searches that used each parameter form.
+type lcdTestServer struct {
+	*httptest.Server
+	queryCalls  atomic.Int32
+	eventsCalls atomic.Int32
+}
+
+func newLCDTestServer(t *testing.T, nodeInfo string, txs func(r *http.Request) (int, string)) *lcdTestServer {
+	t.Helper()
+	s := &lcdTestServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/cosmos/base/tendermint/v1beta1/node_info":
+			_, _ = w.Write(lcdFixture(t, nodeInfo))
+		case "/cosmos/tx/v1beta1/txs":
+			if r.URL.Query().Has("query") {
+				s.queryCalls.Add(1)
+			}
+			if r.URL.Query().Has("events") {
+				s.eventsCalls.Add(1)
+			}
+			status, fixture := txs(r)
+			w.WriteHeader(status)
+			_, _ = w.Write(lcdFixture(t, fixture))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func testLCDPolicy() Policy {
+	p := DefaultPolicy()
+	p.MaxRetries = 0
+	return p
+}
+
+func TestLCDTxSearchQueryParam(t *testing.T) {
+	srv := newLCDTestServer(t, "node_info_v047.json", func(r *http.Request) (int, string) {
+		if got := r.URL.Query().Get("query"); got != "transfer.recipient='"+lcdTestRecipient+"' AND tx.height>=11450700" {
+			t.Errorf("query = %q", got)
+		}
+		return http.StatusOK, "txs_query_v047.json"
+	})
+	c := NewLCDClient(srv.URL, testLCDPolicy())
+
+	res, err := c.TxSearch(context.Background(), "transfer.recipient='"+lcdTestRecipient+"' AND tx.height>=11450700", 1, 30, OrderDesc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.TotalCount != 1 || len(res.Txs) != 1 {
+		t.Fatalf("got total=%d txs=%d, want 1 and 1", res.TotalCount, len(res.Txs))
+	}
+	tx := res.Txs[0]
+	if tx.Height != 11450800 {
+		t.Errorf("height = %d, want 11450800", tx.Height)
+	}
+	if !hasAttr(tx.Events, "transfer", "recipient", lcdTestRecipient) {
+		t.Errorf("plain transfer.recipient not decoded: %+v", tx.Events)
+	}
+	if c.isLegacy() || srv.eventsCalls.Load() != 0 {
+		t.Error("fell back to the legacy events form on an SDK 0.47 gateway")
+	}
+}
+
+func TestLCDTxSearchLegacyFallback(t *testing.T) {
+	srv := newLCDTestServer(t, "node_info_v045.json", func(r *http.Request) (int, string) {
+		if !r.URL.Query().Has("events") {
+			return http.StatusBadRequest, "txs_query_rejected_v045.json"
+		}
+		for _, ev := range r.URL.Query()["events"] {
+			if ev == "tx.height>=11450700" {
+				t.Errorf("range term %q sent as an event", ev)
+			}
+		}
+		return http.StatusOK, "txs_events_v045.json"
+	})
+	c := NewLCDClient(srv.URL, testLCDPolicy())
+	ctx := context.Background()
+	query := "transfer.recipient='" + lcdTestRecipient + "' AND tx.height>=11450700"
+
+	for i := 0; i < 2; i++ {
+		res, err := c.TxSearch(ctx, query, 1, 30, OrderDesc)
+		if err != nil {
+			t.Fatalf("search %d: %v", i, err)
+		}
+		if len(res.Txs) != 1 || res.Txs[0].Height != 11450790 {
+			t.Fatalf("search %d: got %+v", i, res.Txs)
+		}
+		if !hasAttr(res.Txs[0].Events, "transfer", "recipient", lcdTestRecipient) {
+			t.Errorf("base64 transfer.recipient not decoded: %+v", res.Txs[0].Events)
+		}
+	}
+	if got := srv.queryCalls.Load(); got != 1 {
+		t.Errorf("query form tried %d times, want once before switching", got)
+	}
+	if got := srv.eventsCalls.Load(); got != 2 {
+		t.Errorf("events form used %d times, want 2", got)
+	}
+}
+
+func TestLCDTxSearchFallbackOnlyWhenUnsupported(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		fixture  string
+		fallback bool
+	}{
+		{"legacy rejects query", http.StatusBadRequest, "txs_query_rejected_v045.json", true},
+		{"route missing", http.StatusNotFound, "txs_bad_query_v047.json", true},
+		{"not implemented", http.StatusNotImplemented, "txs_bad_query_v047.json", true},
+		{"malformed query", http.StatusBadRequest, "txs_bad_query_v047.json", false},
+		{"server error", http.StatusInternalServerError, "txs_bad_query_v047.json", false},
+		{"rate limited", http.StatusTooManyRequests, "txs_bad_query_v047.json", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newLCDTestServer(t, "node_info_v047.json", func(r *http.Request) (int, string) {
+				if r.URL.Query().Has("events") {
+					return http.StatusOK, "txs_events_v045.json"
+				}
+				return tt.status, tt.fixture
+			})
+			c := NewLCDClient(srv.URL, testLCDPolicy())
+
+			_, err := c.TxSearch(context.Background(), "transfer.recipient='"+lcdTestRecipient+"'", 1, 30, OrderDesc)
+			if tt.fallback {
+				if err != nil || !c.isLegacy() {
+					t.Fatalf("err=%v legacy=%v, want fallback", err, c.isLegacy())
+				}
+				return
+			}
+			if err == nil || c.isLegacy() || srv.eventsCalls.Load() != 0 {
+				t.Fatalf("err=%v legacy=%v, want the error without fallback", err, c.isLegacy())
+			}
+		})
+	}
+}
+
+func hasAttr(events []Event, typ, key, value string) bool {
+	for _, ev := range events {
+		if ev.Type != typ {
+			continue
+		}
+		for _, a := range ev.Attributes {
+			if a.Key == key && a.Value == value {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -9,25 +9,35 @@ import (
 )
 
 type MultiRPCClient struct {
-	clients       []*RPCClient
+	clients       []Client
 	index         int
 	failCount     int
 	failThreshold int
+	health        []EndpointHealth
 	mu            sync.Mutex
 }
 
-func NewMultiRPCClient(endpoints []string, failThreshold int) (*MultiRPCClient, error) {
+func NewMultiRPCClient(endpoints []string, failThreshold int, policy Policy) (*MultiRPCClient, error) {
 	list := sanitizeEndpoints(endpoints)
 	if len(list) == 0 {
 		return nil, errors.New("rpc endpoints is empty")
 	}
+	clients := make([]Client, 0, len(list))
+	for _, ep := range list {
+		clients = append(clients, NewRPCClient(ep, policy))
+	}
+	return NewMultiClient(clients, failThreshold)
+}
+
+// NewMultiClient fails over between already constructed backends, which may
+// mix RPC and LCD clients.
+func NewMultiClient(clients []Client, failThreshold int) (*MultiRPCClient, error) {
+	if len(clients) == 0 {
+		return nil, errors.New("rpc endpoints is empty")
+	}
 	if failThreshold <= 0 {
 		failThreshold = 3
 	}
-	clients := make([]*RPCClient, 0, len(list))
-	for _, ep := range list {
-		clients = append(clients, NewRPCClient(ep))
-	}
 	return &MultiRPCClient{
 		clients:       clients,
 		index:         0,
@@ -36,147 +46,151 @@ func NewMultiRPCClient(endpoints []string, failThreshold int) (*MultiRPCClient,
 	}, nil
 }
 
+// NewClient builds a single client when only one endpoint is configured and a
+// MultiRPCClient otherwise. Every endpoint gets its own Executor for policy.
+func NewClient(rpcEndpoints, lcdEndpoints []string, failThreshold int, policy Policy) (Client, error) {
+	var clients []Client
+	for _, ep := range sanitizeEndpoints(rpcEndpoints) {
+		clients = append(clients, NewRPCClient(ep, policy))
+	}
+	for _, ep := range sanitizeEndpoints(lcdEndpoints) {
+		clients = append(clients, NewLCDClient(ep, policy))
+	}
+	if len(clients) == 1 {
+		return clients[0], nil
+	}
+	return NewMultiClient(clients, failThreshold)
+}
+
 func (m *MultiRPCClient) BaseURL() string {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	return m.clients[m.index].baseURL
+	return m.clients[m.index].BaseURL()
 }
 
 func (m *MultiRPCClient) LatestHeight(ctx context.Context) (int64, error) {
-	m.mu.Lock()
-	start := m.index
-	m.mu.Unlock()
+	return failover(m, func(c Client) (int64, error) { return c.LatestHeight(ctx) })
+}
 
-	var lastErr error
-	for attempts := 0; attempts < len(m.clients); attempts++ {
-		client, idx := m.currentClient()
-		out, err := client.LatestHeight(ctx)
-		if err == nil {
-			m.resetFailures(idx)
-			return out, nil
-		}
-		lastErr = err
-		m.noteFailure(idx)
-		if m.shouldRotate() || len(m.clients) > 1 {
-			m.rotate()
-		}
-		if idx == start && attempts > 0 {
-			break
-		}
-	}
-	return 0, lastErr
+func (m *MultiRPCClient) TxSearch(ctx context.Context, query string, page, perPage int, order TxSearchOrder) (*TxSearchResult, error) {
+	return failover(m, func(c Client) (*TxSearchResult, error) { return c.TxSearch(ctx, query, page, perPage, order) })
 }
 
-func (m *MultiRPCClient) TxSearch(ctx context.Context, query string, page, perPage int) (*TxSearchResult, error) {
-	m.mu.Lock()
-	start := m.index
-	m.mu.Unlock()
+func (m *MultiRPCClient) TxByHash(ctx context.Context, hash string) (*Tx, error) {
+	return failover(m, func(c Client) (*Tx, error) { return c.TxByHash(ctx, hash) })
+}
 
-	var lastErr error
-	for attempts := 0; attempts < len(m.clients); attempts++ {
-		client, idx := m.currentClient()
-		out, err := client.TxSearch(ctx, query, page, perPage)
-		if err == nil {
-			m.resetFailures(idx)
-			return out, nil
-		}
-		lastErr = err
-		m.noteFailure(idx)
-		if m.shouldRotate() || len(m.clients) > 1 {
-			m.rotate()
-		}
-		if idx == start && attempts > 0 {
-			break
-		}
-	}
-	return nil, lastErr
+func (m *MultiRPCClient) BlockTime(ctx context.Context, height int64) (time.Time, error) {
+	return failover(m, func(c Client) (time.Time, error) { return c.BlockTime(ctx, height) })
 }
 
-func (m *MultiRPCClient) TxByHash(ctx context.Context, hash string) (*Tx, error) {
-	m.mu.Lock()
-	start := m.index
-	m.mu.Unlock()
+func (m *MultiRPCClient) BlockTimes(ctx context.Context, heights []int64) (map[int64]time.Time, error) {
+	return failover(m, func(c Client) (map[int64]time.Time, error) { return c.BlockTimes(ctx, heights) })
+}
 
-	var lastErr error
-	for attempts := 0; attempts < len(m.clients); attempts++ {
-		client, idx := m.currentClient()
-		out, err := client.TxByHash(ctx, hash)
-		if err == nil {
-			m.resetFailures(idx)
-			return out, nil
-		}
-		lastErr = err
-		m.noteFailure(idx)
-		if m.shouldRotate() || len(m.clients) > 1 {
-			m.rotate()
-		}
-		if idx == start && attempts > 0 {
-			break
-		}
-	}
-	return nil, lastErr
+func (m *MultiRPCClient) Block(ctx context.Context, height int64) (*Block, error) {
+	return failover(m, func(c Client) (*Block, error) { return c.Block(ctx, height) })
 }
 
-func (m *MultiRPCClient) BlockTime(ctx context.Context, height int64) (time.Time, error) {
-	m.mu.Lock()
-	start := m.index
-	m.mu.Unlock()
+func (m *MultiRPCClient) BlockResults(ctx context.Context, height int64) (*BlockResults, error) {
+	return failover(m, func(c Client) (*BlockResults, error) { return c.BlockResults(ctx, height) })
+}
 
-	var lastErr error
-	for attempts := 0; attempts < len(m.clients); attempts++ {
-		client, idx := m.currentClient()
-		out, err := client.BlockTime(ctx, height)
+// failover calls the current endpoint and, if that fails, the other usable
+// endpoints in turn for this call only. The current endpoint changes only
+// after failThreshold consecutive failures or once it is no longer usable,
+// so a single error does not override the health probe's choice. Retries
+// against a single endpoint are left to that endpoint's Executor.
+func failover[T any](m *MultiRPCClient, call func(Client) (T, error)) (T, error) {
+	client, idx := m.currentClient()
+	out, err := call(client)
+	if err == nil {
+		m.resetFailures(idx)
+		return out, nil
+	}
+	m.noteFailure(idx)
+
+	lastErr := err
+	for i := 1; i < len(m.clients); i++ {
+		next := (idx + i) % len(m.clients)
+		if !m.isUsable(next) {
+			continue
+		}
+		out, err := call(m.clients[next])
 		if err == nil {
-			m.resetFailures(idx)
 			return out, nil
 		}
 		lastErr = err
-		m.noteFailure(idx)
-		if m.shouldRotate() || len(m.clients) > 1 {
-			m.rotate()
-		}
-		if idx == start && attempts > 0 {
-			break
-		}
 	}
-	return time.Time{}, lastErr
+	var zero T
+	return zero, lastErr
 }
 
-func (m *MultiRPCClient) currentClient() (*RPCClient, int) {
+func (m *MultiRPCClient) currentClient() (Client, int) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	return m.clients[m.index], m.index
 }
 
-func (m *MultiRPCClient) resetFailures(idx int) {
+func (m *MultiRPCClient) isUsable(idx int) bool {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	if m.index == idx {
-		m.failCount = 0
-	}
+	return m.usable(idx)
 }
 
-func (m *MultiRPCClient) noteFailure(idx int) {
+func (m *MultiRPCClient) resetFailures(idx int) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	if m.index == idx {
-		m.failCount++
+		m.failCount = 0
 	}
 }
 
-func (m *MultiRPCClient) shouldRotate() bool {
+// noteFailure counts a failure of idx and rotates away from it once it is
+// the current endpoint and should no longer be.
+func (m *MultiRPCClient) noteFailure(idx int) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	return m.failCount >= m.failThreshold
+	if m.index != idx {
+		return
+	}
+	m.failCount++
+	if m.failCount >= m.failThreshold || !m.usable(idx) {
+		m.rotate()
+	}
 }
 
+// rotate moves to the next endpoint, preferring ones the health probe has
+// not ruled out. m.mu must be held.
 func (m *MultiRPCClient) rotate() {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	m.index = (m.index + 1) % len(m.clients)
+	next := (m.index + 1) % len(m.clients)
+	for i := 1; i <= len(m.clients); i++ {
+		idx := (m.index + i) % len(m.clients)
+		if m.usable(idx) {
+			next = idx
+			break
+		}
+	}
+	m.index = next
 	m.failCount = 0
 }
 
+// RPCClientFor returns the RPC client c uses for baseURL, so another caller
+// can share its executor, or nil when c has none for it.
+func RPCClientFor(c Client, baseURL string) *RPCClient {
+	baseURL = strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	clients := []Client{c}
+	if m, ok := c.(*MultiRPCClient); ok {
+		clients = m.clients
+	}
+	for _, client := range clients {
+		if rpc, ok := client.(*RPCClient); ok && rpc.baseURL == baseURL {
+			return rpc
+		}
+	}
+	return nil
+}
+
 func sanitizeEndpoints(endpoints []string) []string {
 	seen := map[string]struct{}{}
 	out := make([]string, 0, len(endpoints))
@@ -194,3 +208,47 @@ func sanitizeEndpoints(endpoints []string) []string {
 	}
 	return out
 }
+
+var ErrQuorumUnavailable = errors.New("not enough endpoints returned the tx")
+
+// Len returns the number of configured endpoints, the largest quorum that
+// TxByHashQuorum can ever satisfy.
+func (m *MultiRPCClient) Len() int {
+	return len(m.clients)
+}
+
+// TxObservation is a tx as returned by one endpoint.
+type TxObservation struct {
+	Endpoint string
+	Tx       *Tx
+}
+
+// TxByHashQuorum fetches hash from every endpoint concurrently and returns the
+// successful observations, failing if fewer than k endpoints answered.
+func (m *MultiRPCClient) TxByHashQuorum(ctx context.Context, hash string, k int) ([]TxObservation, error) {
+	results := make([]TxObservation, len(m.clients))
+	var wg sync.WaitGroup
+	for i, c := range m.clients {
+		wg.Add(1)
+		go func(i int, c Client) {
+			defer wg.Done()
+			tx, err := c.TxByHash(ctx, hash)
+			if err != nil {
+				return
+			}
+			results[i] = TxObservation{Endpoint: c.BaseURL(), Tx: tx}
+		}(i, c)
+	}
+	wg.Wait()
+
+	out := make([]TxObservation, 0, len(results))
+	for _, r := range results {
+		if r.Tx != nil {
+			out = append(out, r)
+		}
+	}
+	if len(out) < k {
+		return out, ErrQuorumUnavailable
+	}
+	return out, nil
+}
@@ -0,0 +1,110 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// stubClient answers LatestHeight with height, or err when set.
+type stubClient struct {
+	name   string
+	height int64
+	err    error
+	calls  int
+}
+
+func (c *stubClient) LatestHeight(ctx context.Context) (int64, error) {
+	c.calls++
+	return c.height, c.err
+}
+
+func (c *stubClient) TxSearch(ctx context.Context, query string, page, perPage int, order TxSearchOrder) (*TxSearchResult, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *stubClient) TxByHash(ctx context.Context, hash string) (*Tx, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *stubClient) BlockTime(ctx context.Context, height int64) (time.Time, error) {
+	return time.Time{}, errors.New("not implemented")
+}
+
+func (c *stubClient) BlockTimes(ctx context.Context, heights []int64) (map[int64]time.Time, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *stubClient) Block(ctx context.Context, height int64) (*Block, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *stubClient) BlockResults(ctx context.Context, height int64) (*BlockResults, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *stubClient) BaseURL() string { return c.name }
+
+func TestFailoverRotatesOnlyAtThreshold(t *testing.T) {
+	primary := &stubClient{name: "a", err: errors.New("timeout")}
+	backup := &stubClient{name: "b", height: 42}
+	m, err := NewMultiClient([]Client{primary, backup}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	for i := 1; i <= 2; i++ {
+		h, err := m.LatestHeight(ctx)
+		if err != nil || h != 42 {
+			t.Fatalf("call %d = %d, %v; want the backup's answer", i, h, err)
+		}
+		if m.BaseURL() != "a" {
+			t.Fatalf("rotated to %s after %d failures, threshold is 3", m.BaseURL(), i)
+		}
+	}
+	if _, err := m.LatestHeight(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if m.BaseURL() != "b" {
+		t.Fatalf("current = %s after 3 failures, want b", m.BaseURL())
+	}
+	primary.calls = 0
+	if _, err := m.LatestHeight(ctx); err != nil || primary.calls != 0 {
+		t.Fatalf("err=%v primary calls=%d, want the backup alone", err, primary.calls)
+	}
+}
+
+func TestFailoverRecoversBeforeThreshold(t *testing.T) {
+	primary := &stubClient{name: "a", err: errors.New("timeout")}
+	backup := &stubClient{name: "b", height: 42}
+	m, _ := NewMultiClient([]Client{primary, backup}, 2)
+	ctx := context.Background()
+
+	if _, err := m.LatestHeight(ctx); err != nil {
+		t.Fatal(err)
+	}
+	primary.err, primary.height = nil, 43
+	if h, err := m.LatestHeight(ctx); err != nil || h != 43 {
+		t.Fatalf("got %d, %v; want the primary again", h, err)
+	}
+	primary.err = errors.New("timeout")
+	if _, err := m.LatestHeight(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if m.BaseURL() != "a" {
+		t.Fatalf("rotated to %s; a success should reset the failure count", m.BaseURL())
+	}
+}
+
+func TestFailoverAllFail(t *testing.T) {
+	errB := errors.New("b down")
+	m, _ := NewMultiClient([]Client{
+		&stubClient{name: "a", err: errors.New("a down")},
+		&stubClient{name: "b", err: errB},
+	}, 3)
+	if _, err := m.LatestHeight(context.Background()); !errors.Is(err, errB) {
+		t.Fatalf("err = %v, want the last endpoint's error", err)
+	}
+}
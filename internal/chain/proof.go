@@ -0,0 +1,227 @@
+package chain
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	dbm "github.com/cometbft/cometbft-db"
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/crypto/merkle"
+	"github.com/cometbft/cometbft/libs/log"
+	"github.com/cometbft/cometbft/light"
+	lightdb "github.com/cometbft/cometbft/light/store/db"
+	"github.com/cometbft/cometbft/types"
+)
+
+// ErrUnproven means a tx could not be proven against a verified header.
+var ErrUnproven = errors.New("tx inclusion not proven")
+
+// LightClientConfig is the root of trust for ProofVerifier. TrustHeight and
+// TrustHash must come from a source other than the RPC providers.
+type LightClientConfig struct {
+	ChainID     string
+	Primary     string
+	Witnesses   []string
+	TrustHeight int64
+	TrustHash   string
+	TrustPeriod time.Duration
+}
+
+// ProvenTx is a tx whose inclusion, result code and block time were checked
+// against light-client verified headers. Sends are decoded from the tx bytes
+// because events are not covered by any header hash.
+type ProvenTx struct {
+	Hash   string
+	Height int64
+	Code   uint32
+	Time   time.Time
+	Sends  []Send
+}
+
+// Send is one bank transfer; Amount uses the SDK coin list format
+// ("100peaka,5uatom").
+type Send struct {
+	Sender    string
+	Recipient string
+	Amount    string
+}
+
+// ProofVerifier fetches inclusion proofs from Primary and checks them against
+// headers verified by a CometBFT light client.
+type ProofVerifier struct {
+	rpc   *RPCClient
+	light *light.Client
+}
+
+// NewProofVerifier fetches proofs through primary, the RPC client for
+// cfg.Primary, so they share its timeouts, retries, circuit breaker and rate
+// limit with every other request to that endpoint.
+func NewProofVerifier(ctx context.Context, cfg LightClientConfig, primary *RPCClient) (*ProofVerifier, error) {
+	hash, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(cfg.TrustHash), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid trust hash: %w", err)
+	}
+	lc, err := light.NewHTTPClient(ctx, cfg.ChainID, light.TrustOptions{
+		Period: cfg.TrustPeriod,
+		Height: cfg.TrustHeight,
+		Hash:   hash,
+	}, cfg.Primary, cfg.Witnesses, lightdb.New(dbm.NewMemDB(), cfg.ChainID),
+		light.Logger(log.NewNopLogger()))
+	if err != nil {
+		return nil, err
+	}
+	return &ProofVerifier{rpc: primary, light: lc}, nil
+}
+
+// ProveTx verifies that hash was included at height and executed with the
+// returned code. The result code is proven by the LastResultsHash of the
+// following block, so height+1 must already exist.
+func (p *ProofVerifier) ProveTx(ctx context.Context, hash string, height int64) (*ProvenTx, error) {
+	h := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(hash)), "0X")
+	var resp txProofResponse
+	if err := p.rpc.getJSON(ctx, "tx", p.rpc.baseURL+"/tx?prove=true&hash=0x"+h, &resp); err != nil {
+		return nil, err
+	}
+	txHeight, err := parseInt64(resp.Result.Height)
+	if err != nil {
+		return nil, err
+	}
+	if txHeight != height {
+		return nil, fmt.Errorf("%w: tx at height %d, expected %d", ErrUnproven, txHeight, height)
+	}
+	txBytes, err := base64.StdEncoding.DecodeString(resp.Result.Proof.Data)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(txBytes)
+	if !strings.EqualFold(hex.EncodeToString(sum[:]), h) {
+		return nil, fmt.Errorf("%w: proof data does not hash to %s", ErrUnproven, h)
+	}
+	proof, err := resp.Result.Proof.toTxProof(txBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	block, err := p.light.VerifyLightBlockAtHeight(ctx, height, now)
+	if err != nil {
+		return nil, fmt.Errorf("%w: header %d: %v", ErrUnproven, height, err)
+	}
+	if err := proof.Validate(block.DataHash); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnproven, err)
+	}
+
+	next, err := p.light.VerifyLightBlockAtHeight(ctx, height+1, now)
+	if err != nil {
+		return nil, fmt.Errorf("%w: header %d: %v", ErrUnproven, height+1, err)
+	}
+	results, err := p.blockResults(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(types.NewResults(results).Hash(), next.LastResultsHash) {
+		return nil, fmt.Errorf("%w: block_results %d do not match results hash", ErrUnproven, height)
+	}
+	index := proof.Proof.Index
+	if index >= int64(len(results)) {
+		return nil, fmt.Errorf("%w: tx index %d out of range", ErrUnproven, index)
+	}
+
+	sends, err := decodeSends(txBytes)
+	if err != nil {
+		return nil, err
+	}
+	return &ProvenTx{
+		Hash:   h,
+		Height: height,
+		Code:   results[index].Code,
+		Time:   block.Time,
+		Sends:  sends,
+	}, nil
+}
+
+func (p *ProofVerifier) blockResults(ctx context.Context, height int64) ([]*abci.ExecTxResult, error) {
+	var resp struct {
+		Result struct {
+			TxsResults []struct {
+				Code      uint32 `json:"code"`
+				Data      string `json:"data"`
+				GasWanted string `json:"gas_wanted"`
+				GasUsed   string `json:"gas_used"`
+			} `json:"txs_results"`
+		} `json:"result"`
+	}
+	endpoint := p.rpc.baseURL + "/block_results?height=" + strconv.FormatInt(height, 10)
+	if err := p.rpc.getJSON(ctx, "block_results", endpoint, &resp); err != nil {
+		return nil, err
+	}
+	out := make([]*abci.ExecTxResult, 0, len(resp.Result.TxsResults))
+	for _, r := range resp.Result.TxsResults {
+		data, err := base64.StdEncoding.DecodeString(r.Data)
+		if err != nil {
+			return nil, err
+		}
+		wanted, _ := strconv.ParseInt(r.GasWanted, 10, 64)
+		used, _ := strconv.ParseInt(r.GasUsed, 10, 64)
+		out = append(out, &abci.ExecTxResult{Code: r.Code, Data: data, GasWanted: wanted, GasUsed: used})
+	}
+	return out, nil
+}
+
+type txProofResponse struct {
+	Result struct {
+		Height string     `json:"height"`
+		Proof  rpcTxProof `json:"proof"`
+	} `json:"result"`
+}
+
+type rpcTxProof struct {
+	RootHash string `json:"root_hash"`
+	Data     string `json:"data"`
+	Proof    struct {
+		Total    string   `json:"total"`
+		Index    string   `json:"index"`
+		LeafHash string   `json:"leaf_hash"`
+		Aunts    []string `json:"aunts"`
+	} `json:"proof"`
+}
+
+func (p rpcTxProof) toTxProof(tx []byte) (*types.TxProof, error) {
+	root, err := hex.DecodeString(p.RootHash)
+	if err != nil {
+		return nil, err
+	}
+	total, err := parseInt64(p.Proof.Total)
+	if err != nil {
+		return nil, err
+	}
+	index, err := parseInt64(p.Proof.Index)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := base64.StdEncoding.DecodeString(p.Proof.LeafHash)
+	if err != nil {
+		return nil, err
+	}
+	aunts := make([][]byte, 0, len(p.Proof.Aunts))
+	for _, a := range p.Proof.Aunts {
+		b, err := base64.StdEncoding.DecodeString(a)
+		if err != nil {
+			return nil, err
+		}
+		aunts = append(aunts, b)
+	}
+	return &types.TxProof{
+		RootHash: root,
+		Data:     tx,
+		Proof:    merkle.Proof{Total: total, Index: index, LeafHash: leaf, Aunts: aunts},
+	}, nil
+}
@@ -11,29 +11,62 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 type RPCClient struct {
-	baseURL string
-	client  *http.Client
+	baseURL  string
+	client   *http.Client
+	exec     *Executor
+	times    *blockTimeCache
+	versions *versionDetector
+
+	mu           sync.Mutex
+	maxPerPage   int
+	legacyHeader bool
 }
 
+// TxSearchOrder is the order_by argument of /tx_search.
+type TxSearchOrder string
+
+const (
+	OrderAsc  TxSearchOrder = "asc"
+	OrderDesc TxSearchOrder = "desc"
+)
+
+// CometBFT caps per_page at 100 unless the node is configured otherwise.
+const defaultMaxPerPage = 100
+
 type Client interface {
 	LatestHeight(ctx context.Context) (int64, error)
-	TxSearch(ctx context.Context, query string, page, perPage int) (*TxSearchResult, error)
+	TxSearch(ctx context.Context, query string, page, perPage int, order TxSearchOrder) (*TxSearchResult, error)
 	TxByHash(ctx context.Context, hash string) (*Tx, error)
 	BlockTime(ctx context.Context, height int64) (time.Time, error)
+	BlockTimes(ctx context.Context, heights []int64) (map[int64]time.Time, error)
+	Block(ctx context.Context, height int64) (*Block, error)
+	BlockResults(ctx context.Context, height int64) (*BlockResults, error)
 	BaseURL() string
 }
 
-func NewRPCClient(baseURL string) *RPCClient {
+// NewRPCClient times out, retries and rate limits every request according to
+// policy.
+func NewRPCClient(baseURL string, policy Policy) *RPCClient {
 	return &RPCClient{
-		baseURL: strings.TrimRight(baseURL, "/"),
-		client:  &http.Client{Timeout: 10 * time.Second},
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		client:     &http.Client{},
+		exec:       NewExecutor(policy),
+		times:      newBlockTimeCache(0),
+		versions:   newVersionDetector(policy.AttrEncoding),
+		maxPerPage: defaultMaxPerPage,
 	}
 }
 
+// Available is false while the endpoint's circuit breaker is open.
+func (c *RPCClient) Available() bool {
+	return c.exec.Available()
+}
+
 func (c *RPCClient) BaseURL() string {
 	return c.baseURL
 }
@@ -41,19 +74,48 @@ func (c *RPCClient) BaseURL() string {
 func (c *RPCClient) LatestHeight(ctx context.Context) (int64, error) {
 	endpoint := c.baseURL + "/status"
 	var resp statusResponse
-	if err := c.getJSON(ctx, endpoint, &resp); err != nil {
+	if err := c.getJSON(ctx, "status", endpoint, &resp); err != nil {
 		return 0, err
 	}
 	return parseInt64(resp.Result.SyncInfo.LatestBlockHeight)
 }
 
-func (c *RPCClient) TxSearch(ctx context.Context, query string, page, perPage int) (*TxSearchResult, error) {
+func (c *RPCClient) Status(ctx context.Context) (*NodeStatus, error) {
+	var resp statusResponse
+	if err := c.getJSON(ctx, "status", c.baseURL+"/status", &resp); err != nil {
+		return nil, err
+	}
+	height, err := parseInt64(resp.Result.SyncInfo.LatestBlockHeight)
+	if err != nil {
+		return nil, err
+	}
+	c.versions.set(resp.Result.NodeInfo.Version)
+	return &NodeStatus{
+		LatestHeight: height,
+		CatchingUp:   resp.Result.SyncInfo.CatchingUp,
+	}, nil
+}
+
+// TxSearch clamps perPage to the largest page size the node has been seen to
+// honour. The page size actually used is returned in TxSearchResult.PerPage so
+// callers can page consistently.
+func (c *RPCClient) TxSearch(ctx context.Context, query string, page, perPage int, order TxSearchOrder) (*TxSearchResult, error) {
 	if page < 1 {
 		page = 1
 	}
 	if perPage < 1 {
 		perPage = 30
 	}
+	if limit := c.pageLimit(); perPage > limit {
+		perPage = limit
+	}
+	if order == "" {
+		order = OrderAsc
+	}
+	enc, err := c.attrEncoding(ctx)
+	if err != nil {
+		return nil, err
+	}
 	u, err := url.Parse(c.baseURL + "/tx_search")
 	if err != nil {
 		return nil, err
@@ -64,15 +126,15 @@ func (c *RPCClient) TxSearch(ctx context.Context, query string, page, perPage in
 	values.Set("prove", "false")
 	values.Set("page", strconv.Itoa(page))
 	values.Set("per_page", strconv.Itoa(perPage))
-	values.Set("order_by", "\"asc\"")
+	values.Set("order_by", "\""+string(order)+"\"")
 	u.RawQuery = values.Encode()
 	endpoint := u.String()
 	var resp txSearchResponse
-	if err := c.getJSON(ctx, endpoint, &resp); err != nil {
+	if err := c.getJSON(ctx, "tx_search", endpoint, &resp); err != nil {
 		return nil, err
 	}
 
-	result := &TxSearchResult{}
+	result := &TxSearchResult{PerPage: perPage}
 	total, err := parseInt64(resp.Result.TotalCount)
 	if err != nil {
 		return nil, err
@@ -89,22 +151,47 @@ func (c *RPCClient) TxSearch(ctx context.Context, query string, page, perPage in
 			Hash:      tx.Hash,
 			Height:    height,
 			Code:      tx.TxResult.Code,
-			Events:    decodeEvents(tx.TxResult.Events),
+			Events:    decodeEvents(tx.TxResult.Events, enc),
 			Timestamp: timestamp,
 		})
 	}
+
+	// A short page that is not the last one means the node capped per_page.
+	got := len(result.Txs)
+	if got > 0 && got < perPage && int64((page-1)*perPage+got) < total {
+		c.setPageLimit(got)
+		result.PerPage = got
+	}
 	return result, nil
 }
 
+func (c *RPCClient) pageLimit() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.maxPerPage
+}
+
+func (c *RPCClient) setPageLimit(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n < c.maxPerPage {
+		c.maxPerPage = n
+	}
+}
+
 func (c *RPCClient) TxByHash(ctx context.Context, hash string) (*Tx, error) {
 	h := strings.TrimSpace(hash)
 	if h == "" {
 		return nil, errors.New("empty tx hash")
 	}
 	h = strings.TrimPrefix(strings.ToUpper(h), "0X")
+	enc, err := c.attrEncoding(ctx)
+	if err != nil {
+		return nil, err
+	}
 	endpoint := c.baseURL + "/tx?hash=0x" + h
 	var resp txByHashResponse
-	if err := c.getJSON(ctx, endpoint, &resp); err != nil {
+	if err := c.getJSON(ctx, "tx", endpoint, &resp); err != nil {
 		return nil, err
 	}
 
@@ -121,45 +208,131 @@ func (c *RPCClient) TxByHash(ctx context.Context, hash string) (*Tx, error) {
 		Hash:      strings.ToUpper(txHash),
 		Height:    height,
 		Code:      resp.Result.TxResult.Code,
-		Events:    decodeEvents(resp.Result.TxResult.Events),
+		Events:    decodeEvents(resp.Result.TxResult.Events, enc),
 		Timestamp: time.Time{},
 	}, nil
 }
 
 func (c *RPCClient) BlockTime(ctx context.Context, height int64) (time.Time, error) {
+	if t, ok := c.times.get(height); ok {
+		return t, nil
+	}
 	endpoint := c.baseURL + "/block?height=" + strconv.FormatInt(height, 10)
 	var resp blockResponse
-	if err := c.getJSON(ctx, endpoint, &resp); err != nil {
+	if err := c.getJSON(ctx, "block", endpoint, &resp); err != nil {
 		return time.Time{}, err
 	}
 	t, err := time.Parse(time.RFC3339, resp.Result.Block.Header.Time)
 	if err != nil {
 		return time.Time{}, err
 	}
+	c.times.put(height, t)
 	return t, nil
 }
 
-func (c *RPCClient) getJSON(ctx context.Context, endpoint string, out any) error {
+func (c *RPCClient) Block(ctx context.Context, height int64) (*Block, error) {
+	endpoint := c.baseURL + "/block?height=" + strconv.FormatInt(height, 10)
+	var resp blockResponse
+	if err := c.getJSON(ctx, "block", endpoint, &resp); err != nil {
+		return nil, err
+	}
+	h, err := parseInt64(resp.Result.Block.Header.Height)
+	if err != nil {
+		return nil, err
+	}
+	t, err := time.Parse(time.RFC3339, resp.Result.Block.Header.Time)
+	if err != nil {
+		return nil, err
+	}
+	c.times.put(h, t)
+	block := &Block{Height: h, Time: t}
+	for _, raw := range resp.Result.Block.Data.Txs {
+		hash, err := hashFromTx(raw)
+		if err != nil {
+			return nil, err
+		}
+		block.TxHashes = append(block.TxHashes, hash)
+	}
+	return block, nil
+}
+
+func (c *RPCClient) BlockResults(ctx context.Context, height int64) (*BlockResults, error) {
+	enc, err := c.attrEncoding(ctx)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := c.baseURL + "/block_results?height=" + strconv.FormatInt(height, 10)
+	var resp blockResultsResponse
+	if err := c.getJSON(ctx, "block_results", endpoint, &resp); err != nil {
+		return nil, err
+	}
+	h, err := parseInt64(resp.Result.Height)
+	if err != nil {
+		return nil, err
+	}
+	r := resp.Result
+	out := &BlockResults{
+		Height:              h,
+		BeginBlockEvents:    decodeEvents(r.BeginBlockEvents, enc),
+		EndBlockEvents:      decodeEvents(r.EndBlockEvents, enc),
+		FinalizeBlockEvents: decodeEvents(r.FinalizeBlockEvents, enc),
+	}
+	for _, res := range r.TxsResults {
+		wanted, _ := strconv.ParseInt(res.GasWanted, 10, 64)
+		used, _ := strconv.ParseInt(res.GasUsed, 10, 64)
+		out.TxResults = append(out.TxResults, TxResult{
+			Code:      res.Code,
+			Codespace: res.Codespace,
+			GasWanted: wanted,
+			GasUsed:   used,
+			Events:    decodeEvents(res.Events, enc),
+		})
+	}
+	return out, nil
+}
+
+func (c *RPCClient) getJSON(ctx context.Context, method, endpoint string, out any) error {
+	return c.exec.Do(ctx, method, func(ctx context.Context) error {
+		return getJSON(ctx, c.client, endpoint, out)
+	})
+}
+
+func getJSON(ctx context.Context, client *http.Client, endpoint string, out any) error {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
 		return err
 	}
-	resp, err := c.client.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		body, _ := io.ReadAll(resp.Body)
-		msg := strings.TrimSpace(string(body))
-		if msg != "" {
-			return fmt.Errorf("rpc http status %d: %s", resp.StatusCode, msg)
+		return &HTTPStatusError{
+			StatusCode: resp.StatusCode,
+			Body:       strings.TrimSpace(string(body)),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
 		}
-		return fmt.Errorf("rpc http status %d", resp.StatusCode)
 	}
 	return json.NewDecoder(resp.Body).Decode(out)
 }
 
+// HTTPStatusError is returned for non-2xx responses. RetryAfter is set from
+// the Retry-After header, typically on 429.
+type HTTPStatusError struct {
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration
+}
+
+func (e *HTTPStatusError) Error() string {
+	if e.Body != "" {
+		return fmt.Sprintf("rpc http status %d: %s", e.StatusCode, e.Body)
+	}
+	return fmt.Sprintf("rpc http status %d", e.StatusCode)
+}
+
 func parseInt64(v string) (int64, error) {
 	if v == "" {
 		return 0, errors.New("empty int string")
@@ -167,21 +340,8 @@ func parseInt64(v string) (int64, error) {
 	return strconv.ParseInt(v, 10, 64)
 }
 
-func decodeEvents(events []rpcEvent) []Event {
-	out := make([]Event, 0, len(events))
-	for _, ev := range events {
-		e := Event{Type: ev.Type}
-		for _, attr := range ev.Attributes {
-			e.Attributes = append(e.Attributes, Attribute{
-				Key:   decodeMaybeBase64(attr.Key),
-				Value: decodeMaybeBase64(attr.Value),
-			})
-		}
-		out = append(out, e)
-	}
-	return out
-}
-
+// decodeMaybeBase64 is the pre-detection heuristic, kept for
+// AttrEncodingLegacy.
 func decodeMaybeBase64(v string) string {
 	b, err := base64.StdEncoding.DecodeString(v)
 	if err != nil {
@@ -210,8 +370,12 @@ func isMostlyPrintable(b []byte) bool {
 
 type statusResponse struct {
 	Result struct {
+		NodeInfo struct {
+			Version string `json:"version"`
+		} `json:"node_info"`
 		SyncInfo struct {
 			LatestBlockHeight string `json:"latest_block_height"`
+			CatchingUp        bool   `json:"catching_up"`
 		} `json:"sync_info"`
 	} `json:"result"`
 }
@@ -235,12 +399,28 @@ type blockResponse struct {
 	Result struct {
 		Block struct {
 			Header struct {
-				Time string `json:"time"`
+				Height string `json:"height"`
+				Time   string `json:"time"`
 			} `json:"header"`
+			Data struct {
+				Txs []string `json:"txs"`
+			} `json:"data"`
 		} `json:"block"`
 	} `json:"result"`
 }
 
+// blockResultsResponse covers 0.34/0.37 (begin/end_block_events) and 0.38
+// (finalize_block_events); fields absent in a version stay empty.
+type blockResultsResponse struct {
+	Result struct {
+		Height              string        `json:"height"`
+		TxsResults          []rpcTxResult `json:"txs_results"`
+		BeginBlockEvents    []rpcEvent    `json:"begin_block_events"`
+		EndBlockEvents      []rpcEvent    `json:"end_block_events"`
+		FinalizeBlockEvents []rpcEvent    `json:"finalize_block_events"`
+	} `json:"result"`
+}
+
 type rpcTx struct {
 	Hash      string      `json:"hash"`
 	Height    string      `json:"height"`
@@ -249,8 +429,11 @@ type rpcTx struct {
 }
 
 type rpcTxResult struct {
-	Code   int        `json:"code"`
-	Events []rpcEvent `json:"events"`
+	Code      int        `json:"code"`
+	Codespace string     `json:"codespace"`
+	GasWanted string     `json:"gas_wanted"`
+	GasUsed   string     `json:"gas_used"`
+	Events    []rpcEvent `json:"events"`
 }
 
 type rpcEvent struct {
@@ -267,6 +450,7 @@ type rpcAttribute struct {
 
 type TxSearchResult struct {
 	TotalCount int64
+	PerPage    int
 	Txs        []Tx
 }
 
@@ -0,0 +1,146 @@
+package chain
+
+import (
+	"errors"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	msgSendURL      = "/cosmos.bank.v1beta1.MsgSend"
+	msgMultiSendURL = "/cosmos.bank.v1beta1.MsgMultiSend"
+	msgExecURL      = "/cosmos.authz.v1beta1.MsgExec"
+
+	// maxExecDepth bounds how deeply nested MsgExec messages are unwrapped.
+	maxExecDepth = 4
+)
+
+var errMalformedTx = errors.New("malformed tx bytes")
+
+// decodeSends extracts bank transfers from raw TxRaw bytes. MsgSend and
+// MsgMultiSend outputs are understood, including those wrapped in an authz
+// MsgExec; other messages are skipped.
+func decodeSends(raw []byte) ([]Send, error) {
+	txRaw, err := protoFields(raw)
+	if err != nil {
+		return nil, err
+	}
+	body, err := protoFields(txRaw.bytes(1))
+	if err != nil {
+		return nil, err
+	}
+	return decodeMsgSends(body.all(1), 0)
+}
+
+// decodeMsgSends decodes the bank transfers in a list of Any messages.
+func decodeMsgSends(anys [][]byte, depth int) ([]Send, error) {
+	var out []Send
+	for _, anyBytes := range anys {
+		msg, err := protoFields(anyBytes)
+		if err != nil {
+			return nil, err
+		}
+		value, err := protoFields(msg.bytes(2))
+		if err != nil {
+			return nil, err
+		}
+		switch string(msg.bytes(1)) {
+		case msgSendURL:
+			amount, err := decodeCoins(value.all(3))
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, Send{
+				Sender:    string(value.bytes(1)),
+				Recipient: string(value.bytes(2)),
+				Amount:    amount,
+			})
+		case msgMultiSendURL:
+			var sender string
+			if inputs := value.all(1); len(inputs) == 1 {
+				in, err := protoFields(inputs[0])
+				if err != nil {
+					return nil, err
+				}
+				sender = string(in.bytes(1))
+			}
+			for _, o := range value.all(2) {
+				output, err := protoFields(o)
+				if err != nil {
+					return nil, err
+				}
+				amount, err := decodeCoins(output.all(2))
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, Send{Sender: sender, Recipient: string(output.bytes(1)), Amount: amount})
+			}
+		case msgExecURL:
+			if depth >= maxExecDepth {
+				return nil, errMalformedTx
+			}
+			// The inner messages are signed by their granter, which MsgSend
+			// carries as from_address, so Sender stays the granter.
+			inner, err := decodeMsgSends(value.all(2), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, inner...)
+		}
+	}
+	return out, nil
+}
+
+func decodeCoins(coins [][]byte) (string, error) {
+	parts := make([]string, 0, len(coins))
+	for _, c := range coins {
+		coin, err := protoFields(c)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, string(coin.bytes(2))+string(coin.bytes(1)))
+	}
+	return strings.Join(parts, ","), nil
+}
+
+// protoMessage holds the length-delimited fields of a protobuf message by
+// field number; other wire types are skipped.
+type protoMessage map[protowire.Number][][]byte
+
+func protoFields(b []byte) (protoMessage, error) {
+	m := protoMessage{}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, errMalformedTx
+		}
+		b = b[n:]
+		if typ == protowire.BytesType {
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return nil, errMalformedTx
+			}
+			m[num] = append(m[num], v)
+			b = b[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return nil, errMalformedTx
+		}
+		b = b[n:]
+	}
+	return m, nil
+}
+
+func (m protoMessage) bytes(num protowire.Number) []byte {
+	if v := m[num]; len(v) > 0 {
+		return v[0]
+	}
+	return nil
+}
+
+func (m protoMessage) all(num protowire.Number) [][]byte {
+	return m[num]
+}
@@ -17,3 +17,15 @@ func DefaultWSEndpoint(rpc string) string {
 	}
 	return ""
 }
+
+// RPCEndpointForWS is the inverse of DefaultWSEndpoint.
+func RPCEndpointForWS(ws string) string {
+	base := strings.TrimSuffix(strings.TrimRight(ws, "/"), "/websocket")
+	if strings.HasPrefix(base, "wss://") {
+		return "https://" + strings.TrimPrefix(base, "wss://")
+	}
+	if strings.HasPrefix(base, "ws://") {
+		return "http://" + strings.TrimPrefix(base, "ws://")
+	}
+	return base
+}
@@ -0,0 +1,160 @@
+package chain
+
+import (
+	"context"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// AttrEncoding is how a node encodes event attribute keys and values.
+// CometBFT 0.34 and older base64-encode them; 0.37 and later send plain text.
+type AttrEncoding string
+
+const (
+	// AttrEncodingAuto detects the encoding from node_info.version.
+	AttrEncodingAuto   AttrEncoding = ""
+	AttrEncodingBase64 AttrEncoding = "base64"
+	AttrEncodingPlain  AttrEncoding = "plain"
+	// AttrEncodingLegacy guesses per value whether it is base64. It can
+	// misdecode plain values that happen to be valid base64 and is only used
+	// when configured explicitly.
+	AttrEncodingLegacy AttrEncoding = "legacy"
+)
+
+// AttrEncodingForVersion maps a CometBFT/Tendermint version such as
+// "0.34.27" or "v0.38.12" to its attribute encoding. Unparseable versions are
+// assumed to be current releases.
+func AttrEncodingForVersion(version string) AttrEncoding {
+	v := strings.TrimPrefix(strings.TrimSpace(version), "v")
+	parts := strings.SplitN(v, ".", 3)
+	if len(parts) < 2 {
+		return AttrEncodingPlain
+	}
+	major, err1 := strconv.Atoi(parts[0])
+	minor, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil {
+		return AttrEncodingPlain
+	}
+	if major == 0 && minor <= 34 {
+		return AttrEncodingBase64
+	}
+	return AttrEncodingPlain
+}
+
+func (e AttrEncoding) decode(v string) string {
+	switch e {
+	case AttrEncodingBase64:
+		b, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return v
+		}
+		return string(b)
+	case AttrEncodingLegacy:
+		return decodeMaybeBase64(v)
+	default:
+		return v
+	}
+}
+
+// versionDetector resolves an endpoint's attribute encoding once, unless one
+// was configured.
+type versionDetector struct {
+	mu         sync.Mutex
+	configured AttrEncoding
+	detected   AttrEncoding
+	version    string
+}
+
+func newVersionDetector(configured AttrEncoding) *versionDetector {
+	return &versionDetector{configured: configured}
+}
+
+// encoding returns the endpoint's encoding, calling fetch for the node version
+// on first use. A failed fetch is not cached.
+func (d *versionDetector) encoding(ctx context.Context, fetch func(ctx context.Context) (string, error)) (AttrEncoding, error) {
+	d.mu.Lock()
+	enc := d.configured
+	if enc == AttrEncodingAuto {
+		enc = d.detected
+	}
+	d.mu.Unlock()
+	if enc != AttrEncodingAuto {
+		return enc, nil
+	}
+
+	version, err := fetch(ctx)
+	if err != nil {
+		return AttrEncodingAuto, err
+	}
+	return d.set(version), nil
+}
+
+func (d *versionDetector) set(version string) AttrEncoding {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.version = version
+	d.detected = AttrEncodingForVersion(version)
+	if d.configured != AttrEncodingAuto {
+		return d.configured
+	}
+	return d.detected
+}
+
+func (d *versionDetector) nodeVersion() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.version
+}
+
+func (c *RPCClient) attrEncoding(ctx context.Context) (AttrEncoding, error) {
+	return c.versions.encoding(ctx, func(ctx context.Context) (string, error) {
+		var resp statusResponse
+		if err := c.getJSON(ctx, "status", c.baseURL+"/status", &resp); err != nil {
+			return "", err
+		}
+		return resp.Result.NodeInfo.Version, nil
+	})
+}
+
+// NodeVersion is the CometBFT version reported by the endpoint, empty until
+// it has been queried.
+func (c *RPCClient) NodeVersion() string {
+	return c.versions.nodeVersion()
+}
+
+func (c *LCDClient) attrEncoding(ctx context.Context) (AttrEncoding, error) {
+	return c.versions.encoding(ctx, func(ctx context.Context) (string, error) {
+		var resp struct {
+			DefaultNodeInfo struct {
+				Version string `json:"version"`
+			} `json:"default_node_info"`
+		}
+		if err := c.getJSON(ctx, "status", c.baseURL+"/cosmos/base/tendermint/v1beta1/node_info", &resp); err != nil {
+			return "", err
+		}
+		return resp.DefaultNodeInfo.Version, nil
+	})
+}
+
+// NodeVersion is the CometBFT version reported by the endpoint, empty until
+// it has been queried.
+func (c *LCDClient) NodeVersion() string {
+	return c.versions.nodeVersion()
+}
+
+func decodeEvents(events []rpcEvent, enc AttrEncoding) []Event {
+	out := make([]Event, 0, len(events))
+	for _, ev := range events {
+		e := Event{Type: ev.Type}
+		for _, attr := range ev.Attributes {
+			e.Attributes = append(e.Attributes, Attribute{
+				Key:   enc.decode(attr.Key),
+				Value: enc.decode(attr.Value),
+			})
+		}
+		out = append(out, e)
+	}
+	return out
+}
@@ -7,69 +7,203 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"net"
+	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
 )
 
+const (
+	defaultWSPingInterval = 20 * time.Second
+	defaultWSReadTimeout  = 60 * time.Second
+	defaultWSWriteTimeout = 10 * time.Second
+)
+
+// StaleConnectionError means nothing, not even a pong, arrived within the
+// read timeout. The connection may be half-open and must be replaced.
+type StaleConnectionError struct {
+	Endpoint string
+	Timeout  time.Duration
+}
+
+func (e *StaleConnectionError) Error() string {
+	return fmt.Sprintf("ws %s stale: no data for %s", e.Endpoint, e.Timeout)
+}
+
+// IsSubscriptionLimitError reports whether err is the node rejecting a
+// subscribe because the client already holds max_subscriptions_per_client.
+func IsSubscriptionLimitError(err error) bool {
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) {
+		return false
+	}
+	return strings.Contains(rpcErr.Data, "max_subscriptions_per_client") ||
+		strings.Contains(rpcErr.Message, "max_subscriptions_per_client")
+}
+
 type WSClient struct {
 	Endpoint string
 	Conn     *websocket.Conn
+	// Encoding of event attributes; detected from the node's /status on
+	// Connect when left as AttrEncodingAuto.
+	Encoding AttrEncoding
+	// PingInterval is how often a ping is sent; ReadTimeout is how long the
+	// connection may stay silent before Read reports it stale.
+	PingInterval time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+
+	// closeMu guards done, so Close may be called from several goroutines
+	// and more than once.
+	closeMu sync.Mutex
+	done    chan struct{}
+	writeMu sync.Mutex
+	nextID  int
 }
 
 func NewWSClient(endpoint string) *WSClient {
-	return &WSClient{Endpoint: endpoint}
+	return &WSClient{
+		Endpoint:     endpoint,
+		PingInterval: defaultWSPingInterval,
+		ReadTimeout:  defaultWSReadTimeout,
+		WriteTimeout: defaultWSWriteTimeout,
+	}
 }
 
 func (c *WSClient) Connect(ctx context.Context) error {
-	dialer := websocket.Dialer{}
+	if c.Encoding == AttrEncodingAuto {
+		var resp statusResponse
+		client := &http.Client{Timeout: 10 * time.Second}
+		if err := getJSON(ctx, client, RPCEndpointForWS(c.Endpoint)+"/status", &resp); err != nil {
+			return fmt.Errorf("detect node version: %w", err)
+		}
+		c.Encoding = AttrEncodingForVersion(resp.Result.NodeInfo.Version)
+	}
+	dialer := websocket.Dialer{HandshakeTimeout: c.WriteTimeout}
 	conn, _, err := dialer.DialContext(ctx, c.Endpoint, nil)
 	if err != nil {
 		return err
 	}
+	done := make(chan struct{})
+	c.closeMu.Lock()
 	c.Conn = conn
+	c.done = done
+	c.closeMu.Unlock()
+	_ = conn.SetReadDeadline(time.Now().Add(c.ReadTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(c.ReadTimeout))
+	})
+	go c.keepalive(conn, done)
 	return nil
 }
 
+// keepalive pings until the client is closed. WriteControl may run
+// concurrently with the reader and other writers.
+func (c *WSClient) keepalive(conn *websocket.Conn, done chan struct{}) {
+	ticker := time.NewTicker(c.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.WriteTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Close stops the keepalive and closes the connection, which unblocks a
+// pending Read. It is safe to call concurrently and repeatedly.
 func (c *WSClient) Close() {
-	if c.Conn != nil {
-		_ = c.Conn.Close()
+	c.closeMu.Lock()
+	defer c.closeMu.Unlock()
+	if c.done == nil {
+		return
 	}
+	close(c.done)
+	c.done = nil
+	_ = c.Conn.Close()
 }
 
 func (c *WSClient) Subscribe(ctx context.Context, query string) error {
+	return c.call(ctx, "subscribe", query)
+}
+
+func (c *WSClient) Unsubscribe(ctx context.Context, query string) error {
+	return c.call(ctx, "unsubscribe", query)
+}
+
+// call writes a request; the node's reply, including any error, arrives
+// through Read. Calls may come from a different goroutine than Read.
+func (c *WSClient) call(ctx context.Context, method, query string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.nextID++
 	payload := map[string]any{
 		"jsonrpc": "2.0",
-		"id":      1,
-		"method":  "subscribe",
+		"id":      c.nextID,
+		"method":  method,
 		"params": map[string]any{
 			"query": query,
 		},
 	}
+	deadline := time.Now().Add(c.WriteTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = c.Conn.SetWriteDeadline(deadline)
 	return c.Conn.WriteJSON(payload)
 }
 
+// Read returns the next message. It returns ctx.Err() promptly once ctx is
+// done and a *StaleConnectionError when the read timeout expires; either way
+// the connection is unusable afterwards.
 func (c *WSClient) Read(ctx context.Context) ([]byte, error) {
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = c.Conn.SetReadDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+
 	_, msg, err := c.Conn.ReadMessage()
-	return msg, err
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return nil, &StaleConnectionError{Endpoint: c.Endpoint, Timeout: c.ReadTimeout}
+		}
+		return nil, err
+	}
+	_ = c.Conn.SetReadDeadline(time.Now().Add(c.ReadTimeout))
+	return msg, nil
 }
 
-func ParseWSTx(msg []byte) (*Tx, bool, error) {
+// ParseWSTx decodes a Tx event; enc is the endpoint's attribute encoding.
+// Tx events carry no block time, so Timestamp is left zero for FillBlockTimes.
+func ParseWSTx(msg []byte, enc AttrEncoding) (*Tx, bool, error) {
 	var env struct {
 		Result struct {
 			Data json.RawMessage `json:"data"`
 		} `json:"result"`
-		Error *struct {
-			Code    int    `json:"code"`
-			Message string `json:"message"`
-		} `json:"error"`
+		Error *RPCError `json:"error"`
 	}
 	if err := json.Unmarshal(msg, &env); err != nil {
 		return nil, false, err
 	}
 	if env.Error != nil {
-		return nil, false, errors.New(env.Error.Message)
+		return nil, false, env.Error
 	}
 	if len(env.Result.Data) == 0 {
 		return nil, false, nil
@@ -109,14 +243,58 @@ func ParseWSTx(msg []byte) (*Tx, bool, error) {
 	}
 
 	return &Tx{
-		Hash:      strings.ToUpper(hash),
-		Height:    height,
-		Code:      data.Value.TxResult.Result.Code,
-		Events:    decodeEvents(data.Value.TxResult.Result.Events),
-		Timestamp: time.Now().UTC(),
+		Hash:   strings.ToUpper(hash),
+		Height: height,
+		Code:   data.Value.TxResult.Result.Code,
+		Events: decodeEvents(data.Value.TxResult.Result.Events, enc),
 	}, true, nil
 }
 
+// ParseWSNewBlock extracts the height and header time from a NewBlock event.
+// TxHashes is left empty.
+func ParseWSNewBlock(msg []byte) (*Block, bool, error) {
+	var env struct {
+		Result struct {
+			Data json.RawMessage `json:"data"`
+		} `json:"result"`
+		Error *RPCError `json:"error"`
+	}
+	if err := json.Unmarshal(msg, &env); err != nil {
+		return nil, false, err
+	}
+	if env.Error != nil {
+		return nil, false, env.Error
+	}
+	if len(env.Result.Data) == 0 {
+		return nil, false, nil
+	}
+
+	var data struct {
+		Type  string `json:"type"`
+		Value struct {
+			Block struct {
+				Header blockHeader `json:"header"`
+			} `json:"block"`
+		} `json:"value"`
+	}
+	if err := json.Unmarshal(env.Result.Data, &data); err != nil {
+		return nil, false, err
+	}
+	if !strings.HasSuffix(data.Type, "NewBlock") {
+		return nil, false, nil
+	}
+
+	height, err := parseInt64(data.Value.Block.Header.Height)
+	if err != nil {
+		return nil, false, err
+	}
+	t, err := time.Parse(time.RFC3339, data.Value.Block.Header.Time)
+	if err != nil {
+		return nil, false, err
+	}
+	return &Block{Height: height, Time: t}, true, nil
+}
+
 func hashFromTx(txBase64 string) (string, error) {
 	b, err := base64.StdEncoding.DecodeString(txBase64)
 	if err != nil {
@@ -0,0 +1,131 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newWSTestServer acknowledges every request and then streams NewBlock
+// events until the client goes away.
+func newWSTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var writeMu sync.Mutex
+		write := func(msg string) error {
+			writeMu.Lock()
+			defer writeMu.Unlock()
+			return conn.WriteMessage(websocket.TextMessage, []byte(msg))
+		}
+		go func() {
+			for {
+				if err := write(`{"jsonrpc":"2.0","id":0,"result":{"data":{"type":"tendermint/event/NewBlock","value":{"block":{"header":{"height":"10"}}}}}}`); err != nil {
+					return
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+			if err := write(`{"jsonrpc":"2.0","id":1,"result":{}}`); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func connectTestClient(t *testing.T, srv *httptest.Server) *WSClient {
+	t.Helper()
+	c := NewWSClient("ws" + strings.TrimPrefix(srv.URL, "http") + "/websocket")
+	c.Encoding = AttrEncodingPlain
+	c.PingInterval = 5 * time.Millisecond
+	if err := c.Connect(context.Background()); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	return c
+}
+
+func TestWSClientConcurrentClose(t *testing.T) {
+	srv := newWSTestServer(t)
+	c := connectTestClient(t, srv)
+	ctx := context.Background()
+
+	readErr := make(chan error, 1)
+	go func() {
+		for {
+			if _, err := c.Read(ctx); err != nil {
+				readErr <- err
+				return
+			}
+		}
+	}()
+	go func() {
+		for i := 0; i < 20; i++ {
+			if err := c.Subscribe(ctx, "tm.event='Tx'"); err != nil {
+				return
+			}
+		}
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Close()
+		}()
+	}
+	wg.Wait()
+	c.Close()
+
+	select {
+	case err := <-readErr:
+		if err == nil {
+			t.Fatal("Read returned nil error after Close")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Read still blocked after Close")
+	}
+}
+
+func TestWSClientReadHonoursContext(t *testing.T) {
+	srv := newWSTestServer(t)
+	c := connectTestClient(t, srv)
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	deadline := time.After(2 * time.Second)
+	for {
+		_, err := c.Read(ctx)
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+		if err != nil {
+			t.Fatalf("Read error = %v, want context.Canceled", err)
+		}
+		select {
+		case <-deadline:
+			t.Fatal("Read kept returning messages after cancel")
+		default:
+		}
+	}
+}
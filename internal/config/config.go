@@ -22,28 +22,87 @@ type Config struct {
 	Chain struct {
 		ChainID      string   `yaml:"chain_id"`
 		RPCEndpoints []string `yaml:"rpc_endpoints"`
+		LCDEndpoints []string `yaml:"lcd_endpoints"`
 		WSEndpoints  []string `yaml:"ws_endpoints"`
 		Denom        string   `yaml:"denom"`
 		Decimals     int      `yaml:"decimals"`
 		Bech32Prefix string   `yaml:"bech32_prefix"`
 		ConfirmDepth int      `yaml:"confirm_depth"`
+		// AttrEncoding forces event attribute decoding (base64, plain or
+		// legacy); empty detects it from each node's version.
+		AttrEncoding string `yaml:"attr_encoding"`
+		// Payments of at least QuorumMinAmount (peaka) must be confirmed by
+		// QuorumSize endpoints. Disabled when QuorumSize < 2.
+		QuorumMinAmount string `yaml:"quorum_min_amount"`
+		QuorumSize      int    `yaml:"quorum_size"`
+		// RPCPolicy applies per endpoint to every RPC and LCD request.
+		RPCPolicy struct {
+			TimeoutMs              int64            `yaml:"timeout_ms"`
+			MethodTimeoutsMs       map[string]int64 `yaml:"method_timeouts_ms"`
+			MaxRetries             int              `yaml:"max_retries"`
+			BackoffBaseMs          int64            `yaml:"backoff_base_ms"`
+			BackoffMaxMs           int64            `yaml:"backoff_max_ms"`
+			BreakerThreshold       int              `yaml:"breaker_threshold"`
+			BreakerCooldownSeconds int64            `yaml:"breaker_cooldown_seconds"`
+			RateLimitRPS           float64          `yaml:"rate_limit_rps"`
+			RateBurst              int              `yaml:"rate_burst"`
+		} `yaml:"rpc_policy"`
+		// LightClient makes settlement require tx inclusion proofs checked
+		// against light-client verified headers.
+		LightClient struct {
+			Enabled          bool     `yaml:"enabled"`
+			Primary          string   `yaml:"primary"`
+			Witnesses        []string `yaml:"witnesses"`
+			TrustHeight      int64    `yaml:"trust_height"`
+			TrustHash        string   `yaml:"trust_hash"`
+			TrustPeriodHours int64    `yaml:"trust_period_hours"`
+		} `yaml:"light_client"`
 	} `yaml:"chain"`
 	Orders struct {
-		MinCredit  int64 `yaml:"min_credit"`
-		TTLMinutes int   `yaml:"ttl_minutes"`
+		MinCredit       int64  `yaml:"min_credit"`
+		TTLMinutes      int    `yaml:"ttl_minutes"`
+		QuoteSecret     string `yaml:"quote_secret"`
+		QuoteTTLSeconds int64  `yaml:"quote_ttl_seconds"`
 	} `yaml:"orders"`
 	Worker struct {
-		StartHeight          int64 `yaml:"start_height"`
-		RewindBlocks         int64 `yaml:"rewind_blocks"`
-		MaxBlocksPerTick     int64 `yaml:"max_blocks_per_tick"`
-		IntervalSeconds      int64 `yaml:"interval_seconds"`
-		PerPage              int   `yaml:"per_page"`
-		WSBackfillBlocks     int64 `yaml:"ws_backfill_blocks"`
-		RPCFailoverThreshold int   `yaml:"rpc_failover_threshold"`
-		WSFailoverThreshold  int   `yaml:"ws_failover_threshold"`
+		StartHeight           int64  `yaml:"start_height"`
+		MaxBlocksPerTick      int64  `yaml:"max_blocks_per_tick"`
+		IntervalSeconds       int64  `yaml:"interval_seconds"`
+		LateWatchDays         int64  `yaml:"late_watch_days"`
+		OrphanWatchDays       int64  `yaml:"orphan_watch_days"`
+		LateScanIntervalSec   int64  `yaml:"late_scan_interval_seconds"`
+		AddressLookahead      int64  `yaml:"address_lookahead"`
+		LookaheadScanBudget   int    `yaml:"lookahead_scan_addresses"`
+		PerPage               int    `yaml:"per_page"`
+		WSBackfillBlocks      int64  `yaml:"ws_backfill_blocks"`
+		WSBackfillMaxBlocks   int64  `yaml:"ws_backfill_max_blocks"`
+		WSBackfillChunkBlocks int64  `yaml:"ws_backfill_chunk_blocks"`
+		RPCFailoverThreshold  int    `yaml:"rpc_failover_threshold"`
+		RPCHealthIntervalSec  int64  `yaml:"rpc_health_interval_seconds"`
+		RPCMaxLagBlocks       int64  `yaml:"rpc_max_lag_blocks"`
+		WSFailoverThreshold   int    `yaml:"ws_failover_threshold"`
+		WSRedundancy          int    `yaml:"ws_redundancy"`
+		WSMaxSubscriptions    int    `yaml:"ws_max_subscriptions"`
+		WSSubscriptionSyncSec int64  `yaml:"ws_subscription_sync_seconds"`
+		WSPingIntervalSec     int64  `yaml:"ws_ping_interval_seconds"`
+		WSReadTimeoutSec      int64  `yaml:"ws_read_timeout_seconds"`
+		ScanMode              string `yaml:"scan_mode"`
 	} `yaml:"worker"`
 	Pricing struct {
-		FixedCreditPerDora int64 `yaml:"fixed_credit_per_dora"`
+		Mode               string `yaml:"mode"`
+		FixedCreditPerDora int64  `yaml:"fixed_credit_per_dora"`
+		Fiat               struct {
+			Currency     string `yaml:"currency"`
+			CreditPrice  string `yaml:"credit_price"`
+			DoraPrice    string `yaml:"dora_price"`
+			FeedURL      string `yaml:"feed_url"`
+			FeedPath     string `yaml:"feed_path"`
+			CacheSeconds int64  `yaml:"cache_seconds"`
+		} `yaml:"fiat"`
+		Tiers []struct {
+			MinCredit   int64 `yaml:"min_credit"`
+			DiscountBps int64 `yaml:"discount_bps"`
+		} `yaml:"tiers"`
 	} `yaml:"pricing"`
 }
 
@@ -73,9 +132,28 @@ func Load(path string) (*Config, error) {
 	if cfg.DB.DSN == "" {
 		return nil, errors.New("db.dsn is required")
 	}
-	if cfg.Chain.ChainID == "" || len(cfg.Chain.RPCEndpoints) == 0 || cfg.Chain.Denom == "" {
+	if cfg.Chain.ChainID == "" || len(cfg.Chain.RPCEndpoints)+len(cfg.Chain.LCDEndpoints) == 0 || cfg.Chain.Denom == "" {
 		return nil, errors.New("chain config is incomplete")
 	}
+	switch cfg.Chain.AttrEncoding {
+	case "", "auto", "base64", "plain", "legacy":
+	default:
+		return nil, errors.New("chain.attr_encoding must be auto, base64, plain or legacy")
+	}
+	if cfg.Chain.AttrEncoding == "auto" {
+		cfg.Chain.AttrEncoding = ""
+	}
+	if lc := cfg.Chain.LightClient; lc.Enabled {
+		if lc.Primary == "" || len(lc.Witnesses) == 0 || lc.TrustHeight <= 0 || lc.TrustHash == "" || lc.TrustPeriodHours <= 0 {
+			return nil, errors.New("chain.light_client config is incomplete")
+		}
+	}
+	if cfg.Pricing.Mode == "fiat" {
+		f := cfg.Pricing.Fiat
+		if f.Currency == "" || f.CreditPrice == "" || (f.FeedURL == "" && f.DoraPrice == "") {
+			return nil, errors.New("pricing.fiat config is incomplete")
+		}
+	}
 	return &cfg, nil
 }
 
@@ -95,6 +173,9 @@ func applyEnvOverrides(cfg *Config) {
 	if v := os.Getenv("RPC_ENDPOINTS"); v != "" {
 		cfg.Chain.RPCEndpoints = splitCommaList(v)
 	}
+	if v := os.Getenv("LCD_ENDPOINTS"); v != "" {
+		cfg.Chain.LCDEndpoints = splitCommaList(v)
+	}
 	if v := os.Getenv("WS_ENDPOINTS"); v != "" {
 		cfg.Chain.WSEndpoints = splitCommaList(v)
 	}
@@ -107,39 +188,143 @@ func applyEnvOverrides(cfg *Config) {
 	if v := os.Getenv("CONFIRM_DEPTH"); v != "" {
 		cfg.Chain.ConfirmDepth = atoiOr(cfg.Chain.ConfirmDepth, v)
 	}
+	if v := os.Getenv("ATTR_ENCODING"); v != "" {
+		cfg.Chain.AttrEncoding = v
+	}
+	if v := os.Getenv("QUORUM_MIN_AMOUNT"); v != "" {
+		cfg.Chain.QuorumMinAmount = v
+	}
+	if v := os.Getenv("QUORUM_SIZE"); v != "" {
+		cfg.Chain.QuorumSize = atoiOr(cfg.Chain.QuorumSize, v)
+	}
+	if v := os.Getenv("RPC_TIMEOUT_MS"); v != "" {
+		cfg.Chain.RPCPolicy.TimeoutMs = atoi64Or(cfg.Chain.RPCPolicy.TimeoutMs, v)
+	}
+	if v := os.Getenv("RPC_MAX_RETRIES"); v != "" {
+		cfg.Chain.RPCPolicy.MaxRetries = atoiOr(cfg.Chain.RPCPolicy.MaxRetries, v)
+	}
+	if v := os.Getenv("RPC_RATE_LIMIT_RPS"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			cfg.Chain.RPCPolicy.RateLimitRPS = f
+		}
+	}
+	if v := os.Getenv("RPC_RATE_BURST"); v != "" {
+		cfg.Chain.RPCPolicy.RateBurst = atoiOr(cfg.Chain.RPCPolicy.RateBurst, v)
+	}
+	if v := os.Getenv("LIGHT_CLIENT_ENABLED"); v != "" {
+		cfg.Chain.LightClient.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("LIGHT_CLIENT_PRIMARY"); v != "" {
+		cfg.Chain.LightClient.Primary = v
+	}
+	if v := os.Getenv("LIGHT_CLIENT_WITNESSES"); v != "" {
+		cfg.Chain.LightClient.Witnesses = splitCommaList(v)
+	}
+	if v := os.Getenv("LIGHT_CLIENT_TRUST_HEIGHT"); v != "" {
+		cfg.Chain.LightClient.TrustHeight = atoi64Or(cfg.Chain.LightClient.TrustHeight, v)
+	}
+	if v := os.Getenv("LIGHT_CLIENT_TRUST_HASH"); v != "" {
+		cfg.Chain.LightClient.TrustHash = v
+	}
 	if v := os.Getenv("MIN_CREDIT"); v != "" {
 		cfg.Orders.MinCredit = atoi64Or(cfg.Orders.MinCredit, v)
 	}
 	if v := os.Getenv("ORDER_TTL_MINUTES"); v != "" {
 		cfg.Orders.TTLMinutes = atoiOr(cfg.Orders.TTLMinutes, v)
 	}
+	if v := os.Getenv("QUOTE_SECRET"); v != "" {
+		cfg.Orders.QuoteSecret = v
+	}
+	if v := os.Getenv("QUOTE_TTL_SECONDS"); v != "" {
+		cfg.Orders.QuoteTTLSeconds = atoi64Or(cfg.Orders.QuoteTTLSeconds, v)
+	}
 	if v := os.Getenv("WORKER_START_HEIGHT"); v != "" {
 		cfg.Worker.StartHeight = atoi64Or(cfg.Worker.StartHeight, v)
 	}
-	if v := os.Getenv("WORKER_REWIND_BLOCKS"); v != "" {
-		cfg.Worker.RewindBlocks = atoi64Or(cfg.Worker.RewindBlocks, v)
-	}
 	if v := os.Getenv("WORKER_MAX_BLOCKS_PER_TICK"); v != "" {
 		cfg.Worker.MaxBlocksPerTick = atoi64Or(cfg.Worker.MaxBlocksPerTick, v)
 	}
 	if v := os.Getenv("WORKER_INTERVAL_SECONDS"); v != "" {
 		cfg.Worker.IntervalSeconds = atoi64Or(cfg.Worker.IntervalSeconds, v)
 	}
+	if v := os.Getenv("WORKER_LATE_WATCH_DAYS"); v != "" {
+		cfg.Worker.LateWatchDays = atoi64Or(cfg.Worker.LateWatchDays, v)
+	}
+	if v := os.Getenv("WORKER_ORPHAN_WATCH_DAYS"); v != "" {
+		cfg.Worker.OrphanWatchDays = atoi64Or(cfg.Worker.OrphanWatchDays, v)
+	}
+	if v := os.Getenv("WORKER_LATE_SCAN_INTERVAL_SECONDS"); v != "" {
+		cfg.Worker.LateScanIntervalSec = atoi64Or(cfg.Worker.LateScanIntervalSec, v)
+	}
+	if v := os.Getenv("WORKER_ADDRESS_LOOKAHEAD"); v != "" {
+		cfg.Worker.AddressLookahead = atoi64Or(cfg.Worker.AddressLookahead, v)
+	}
+	if v := os.Getenv("WORKER_LOOKAHEAD_SCAN_ADDRESSES"); v != "" {
+		cfg.Worker.LookaheadScanBudget = atoiOr(cfg.Worker.LookaheadScanBudget, v)
+	}
 	if v := os.Getenv("WORKER_PER_PAGE"); v != "" {
 		cfg.Worker.PerPage = atoiOr(cfg.Worker.PerPage, v)
 	}
 	if v := os.Getenv("WORKER_WS_BACKFILL_BLOCKS"); v != "" {
 		cfg.Worker.WSBackfillBlocks = atoi64Or(cfg.Worker.WSBackfillBlocks, v)
 	}
+	if v := os.Getenv("WORKER_WS_BACKFILL_MAX_BLOCKS"); v != "" {
+		cfg.Worker.WSBackfillMaxBlocks = atoi64Or(cfg.Worker.WSBackfillMaxBlocks, v)
+	}
+	if v := os.Getenv("WORKER_WS_BACKFILL_CHUNK_BLOCKS"); v != "" {
+		cfg.Worker.WSBackfillChunkBlocks = atoi64Or(cfg.Worker.WSBackfillChunkBlocks, v)
+	}
 	if v := os.Getenv("WORKER_RPC_FAILOVER_THRESHOLD"); v != "" {
 		cfg.Worker.RPCFailoverThreshold = atoiOr(cfg.Worker.RPCFailoverThreshold, v)
 	}
+	if v := os.Getenv("WORKER_RPC_HEALTH_INTERVAL_SECONDS"); v != "" {
+		cfg.Worker.RPCHealthIntervalSec = atoi64Or(cfg.Worker.RPCHealthIntervalSec, v)
+	}
+	if v := os.Getenv("WORKER_RPC_MAX_LAG_BLOCKS"); v != "" {
+		cfg.Worker.RPCMaxLagBlocks = atoi64Or(cfg.Worker.RPCMaxLagBlocks, v)
+	}
 	if v := os.Getenv("WORKER_WS_FAILOVER_THRESHOLD"); v != "" {
 		cfg.Worker.WSFailoverThreshold = atoiOr(cfg.Worker.WSFailoverThreshold, v)
 	}
+	if v := os.Getenv("WORKER_WS_REDUNDANCY"); v != "" {
+		cfg.Worker.WSRedundancy = atoiOr(cfg.Worker.WSRedundancy, v)
+	}
+	if v := os.Getenv("WORKER_WS_MAX_SUBSCRIPTIONS"); v != "" {
+		cfg.Worker.WSMaxSubscriptions = atoiOr(cfg.Worker.WSMaxSubscriptions, v)
+	}
+	if v := os.Getenv("WORKER_WS_SUBSCRIPTION_SYNC_SECONDS"); v != "" {
+		cfg.Worker.WSSubscriptionSyncSec = atoi64Or(cfg.Worker.WSSubscriptionSyncSec, v)
+	}
+	if v := os.Getenv("WORKER_WS_PING_INTERVAL_SECONDS"); v != "" {
+		cfg.Worker.WSPingIntervalSec = atoi64Or(cfg.Worker.WSPingIntervalSec, v)
+	}
+	if v := os.Getenv("WORKER_WS_READ_TIMEOUT_SECONDS"); v != "" {
+		cfg.Worker.WSReadTimeoutSec = atoi64Or(cfg.Worker.WSReadTimeoutSec, v)
+	}
+	if v := os.Getenv("WORKER_SCAN_MODE"); v != "" {
+		cfg.Worker.ScanMode = v
+	}
 	if v := os.Getenv("FIXED_CREDIT_PER_DORA"); v != "" {
 		cfg.Pricing.FixedCreditPerDora = atoi64Or(cfg.Pricing.FixedCreditPerDora, v)
 	}
+	if v := os.Getenv("PRICING_MODE"); v != "" {
+		cfg.Pricing.Mode = v
+	}
+	if v := os.Getenv("FIAT_CURRENCY"); v != "" {
+		cfg.Pricing.Fiat.Currency = v
+	}
+	if v := os.Getenv("FIAT_CREDIT_PRICE"); v != "" {
+		cfg.Pricing.Fiat.CreditPrice = v
+	}
+	if v := os.Getenv("FIAT_DORA_PRICE"); v != "" {
+		cfg.Pricing.Fiat.DoraPrice = v
+	}
+	if v := os.Getenv("PRICE_FEED_URL"); v != "" {
+		cfg.Pricing.Fiat.FeedURL = v
+	}
+	if v := os.Getenv("PRICE_FEED_PATH"); v != "" {
+		cfg.Pricing.Fiat.FeedPath = v
+	}
 }
 
 func splitCommaList(v string) []string {
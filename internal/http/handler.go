@@ -17,21 +17,39 @@ import (
 )
 
 type Handler struct {
-	Orders *services.OrderService
-	Chain  chain.Client
+	Orders   *services.OrderService
+	Products *services.ProductService
+	Promos   *services.PromoService
+	Chain    chain.Client
 }
 
 type createOrderRequest struct {
-	Credit int64 `json:"credit"`
+	Credit     int64  `json:"credit"`
+	ProductID  string `json:"productId,omitempty"`
+	Quantity   int64  `json:"quantity,omitempty"`
+	QuoteToken string `json:"quoteToken,omitempty"`
+	PromoCode  string `json:"promoCode,omitempty"`
 }
 
 type createOrderResponse struct {
 	OrderID          string          `json:"orderId"`
+	Credit           int64           `json:"credit"`
 	AmountPeaka      string          `json:"amountPeaka"`
 	Denom            string          `json:"denom"`
 	RecipientAddress string          `json:"recipientAddress"`
 	ExpiresAt        string          `json:"expiresAt"`
 	PriceSnapshot    json.RawMessage `json:"priceSnapshot"`
+	ProductSnapshot  json.RawMessage `json:"productSnapshot,omitempty"`
+}
+
+type quoteResponse struct {
+	Credit        int64           `json:"credit"`
+	AmountPeaka   string          `json:"amountPeaka"`
+	AmountDora    string          `json:"amountDora"`
+	Denom         string          `json:"denom"`
+	PriceSnapshot json.RawMessage `json:"priceSnapshot"`
+	QuoteToken    string          `json:"quoteToken,omitempty"`
+	ExpiresAt     string          `json:"expiresAt,omitempty"`
 }
 
 type orderResponse struct {
@@ -43,25 +61,37 @@ type orderResponse struct {
 	PaidAt           string `json:"paidAt,omitempty"`
 	TxHash           string `json:"txHash,omitempty"`
 	CreditIssued     *int64 `json:"creditIssued,omitempty"`
+	ProductID        string `json:"productId,omitempty"`
+	Quantity         *int64 `json:"quantity,omitempty"`
 }
 
 type adminOrderResponse struct {
-	OrderID          string `json:"orderId"`
-	UserID           string `json:"userId"`
-	Status           string `json:"status"`
-	AmountPeaka      string `json:"amountPeaka"`
-	Denom            string `json:"denom"`
-	RecipientAddress string `json:"recipientAddress"`
-	ExpiresAt        string `json:"expiresAt"`
-	PaidAt           string `json:"paidAt,omitempty"`
-	TxHash           string `json:"txHash,omitempty"`
-	CreditIssued     *int64 `json:"creditIssued,omitempty"`
-	CreatedAt        string `json:"createdAt"`
-	UpdatedAt        string `json:"updatedAt"`
+	OrderID          string               `json:"orderId"`
+	UserID           string               `json:"userId"`
+	Status           string               `json:"status"`
+	AmountPeaka      string               `json:"amountPeaka"`
+	Denom            string               `json:"denom"`
+	RecipientAddress string               `json:"recipientAddress"`
+	ExpiresAt        string               `json:"expiresAt"`
+	PaidAt           string               `json:"paidAt,omitempty"`
+	TxHash           string               `json:"txHash,omitempty"`
+	CreditIssued     *int64               `json:"creditIssued,omitempty"`
+	ProductID        string               `json:"productId,omitempty"`
+	Quantity         *int64               `json:"quantity,omitempty"`
+	Quotes           []orderQuoteResponse `json:"quotes,omitempty"`
+	CreatedAt        string               `json:"createdAt"`
+	UpdatedAt        string               `json:"updatedAt"`
+}
+
+type orderQuoteResponse struct {
+	AmountPeaka   string          `json:"amountPeaka"`
+	PriceSnapshot json.RawMessage `json:"priceSnapshot"`
+	ExpiresAt     string          `json:"expiresAt"`
+	ReplacedAt    string          `json:"replacedAt"`
 }
 
-func NewHandler(orders *services.OrderService, chainClient chain.Client) *Handler {
-	return &Handler{Orders: orders, Chain: chainClient}
+func NewHandler(orders *services.OrderService, products *services.ProductService, promos *services.PromoService, chainClient chain.Client) *Handler {
+	return &Handler{Orders: orders, Products: products, Promos: promos, Chain: chainClient}
 }
 
 func (h *Handler) CreateOrder(w http.ResponseWriter, r *http.Request) {
@@ -72,13 +102,32 @@ func (h *Handler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	}
 
 	userID := r.Header.Get("X-User-Id")
-	order, err := h.Orders.CreateOrder(r.Context(), userID, req.Credit)
+	order, err := h.Orders.CreateOrder(r.Context(), services.CreateOrderParams{
+		UserID:     userID,
+		Credit:     req.Credit,
+		ProductID:  req.ProductID,
+		Quantity:   req.Quantity,
+		QuoteToken: req.QuoteToken,
+		PromoCode:  req.PromoCode,
+	})
 	if err != nil {
 		switch {
 		case errors.Is(err, services.ErrMissingUserID):
 			writeError(w, http.StatusUnauthorized, "missing user id")
 		case errors.Is(err, services.ErrInvalidCredit):
 			writeError(w, http.StatusBadRequest, "credit below minimum")
+		case errors.Is(err, services.ErrInvalidQuote):
+			writeError(w, http.StatusBadRequest, "invalid quote token")
+		case errors.Is(err, services.ErrQuoteExpired):
+			writeError(w, http.StatusConflict, "quote expired")
+		case errors.Is(err, services.ErrProductInactive):
+			writeError(w, http.StatusBadRequest, "product not available")
+		case errors.Is(err, services.ErrInvalidQuantity):
+			writeError(w, http.StatusBadRequest, "invalid quantity")
+		case errors.Is(err, services.ErrInvalidPromo):
+			writeError(w, http.StatusBadRequest, "invalid promo code")
+		case errors.Is(err, services.ErrPromoExhausted):
+			writeError(w, http.StatusConflict, "promo code usage limit reached")
 		case errors.Is(err, services.ErrXpubNotConfigured):
 			writeError(w, http.StatusPreconditionFailed, "wallet xpub not configured")
 		default:
@@ -89,12 +138,96 @@ func (h *Handler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 
 	resp := createOrderResponse{
 		OrderID:          order.OrderID,
+		Credit:           order.CreditRequested,
 		AmountPeaka:      order.AmountPeaka,
 		Denom:            order.Denom,
 		RecipientAddress: order.RecipientAddress,
 		ExpiresAt:        order.ExpiresAt.Format(time.RFC3339),
 		PriceSnapshot:    json.RawMessage(order.PriceSnapshot),
 	}
+	if order.ProductSnapshot != nil {
+		resp.ProductSnapshot = json.RawMessage(*order.ProductSnapshot)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) RequoteOrder(w http.ResponseWriter, r *http.Request) {
+	orderID := chi.URLParam(r, "orderId")
+	if orderID == "" {
+		writeError(w, http.StatusBadRequest, "missing order id")
+		return
+	}
+
+	userID := r.Header.Get("X-User-Id")
+	order, err := h.Orders.RequoteOrder(r.Context(), userID, orderID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrMissingUserID):
+			writeError(w, http.StatusUnauthorized, "missing user id")
+		case errors.Is(err, pgx.ErrNoRows), errors.Is(err, services.ErrOrderNotOwned):
+			writeError(w, http.StatusNotFound, "order not found")
+		case errors.Is(err, services.ErrNotRequotable):
+			writeError(w, http.StatusConflict, "order cannot be requoted")
+		case errors.Is(err, services.ErrProductInactive):
+			writeError(w, http.StatusConflict, "product not available")
+		case errors.Is(err, services.ErrInvalidPromo):
+			writeError(w, http.StatusConflict, "invalid promo code")
+		case errors.Is(err, services.ErrPromoExhausted):
+			writeError(w, http.StatusConflict, "promo code usage limit reached")
+		default:
+			writeError(w, http.StatusInternalServerError, "requote order failed")
+		}
+		return
+	}
+
+	resp := createOrderResponse{
+		OrderID:          order.OrderID,
+		Credit:           order.CreditRequested,
+		AmountPeaka:      order.AmountPeaka,
+		Denom:            order.Denom,
+		RecipientAddress: order.RecipientAddress,
+		ExpiresAt:        order.ExpiresAt.Format(time.RFC3339),
+		PriceSnapshot:    json.RawMessage(order.PriceSnapshot),
+	}
+	if order.ProductSnapshot != nil {
+		resp.ProductSnapshot = json.RawMessage(*order.ProductSnapshot)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) GetQuote(w http.ResponseWriter, r *http.Request) {
+	credit, err := strconv.ParseInt(r.URL.Query().Get("credit"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid credit")
+		return
+	}
+
+	quote, err := h.Orders.Quote(r.Context(), credit)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCredit) {
+			writeError(w, http.StatusBadRequest, "credit below minimum")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "quote failed")
+		return
+	}
+
+	snapJSON, err := json.Marshal(quote.Snapshot)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "quote failed")
+		return
+	}
+	resp := quoteResponse{
+		Credit:        quote.Credit,
+		AmountPeaka:   quote.AmountPeaka,
+		AmountDora:    quote.AmountDora,
+		Denom:         quote.Denom,
+		PriceSnapshot: snapJSON,
+		QuoteToken:    quote.Token,
+	}
+	if !quote.ExpiresAt.IsZero() {
+		resp.ExpiresAt = quote.ExpiresAt.Format(time.RFC3339)
+	}
 	writeJSON(w, http.StatusOK, resp)
 }
 
@@ -129,6 +262,10 @@ func (h *Handler) GetOrder(w http.ResponseWriter, r *http.Request) {
 	if order.TxHash != nil {
 		resp.TxHash = *order.TxHash
 	}
+	if order.ProductID != nil {
+		resp.ProductID = *order.ProductID
+		resp.Quantity = order.Quantity
+	}
 
 	writeJSON(w, http.StatusOK, resp)
 }
@@ -196,6 +333,10 @@ func (h *Handler) AdminVerifyTx(w http.ResponseWriter, r *http.Request) {
 
 		status, updated, err := h.Orders.ApplyPayment(r.Context(), order, *tx, t.Amount, t.Sender)
 		if err != nil {
+			if errors.Is(err, chain.ErrUnproven) {
+				writeError(w, http.StatusUnprocessableEntity, "tx inclusion not proven")
+				return
+			}
 			writeError(w, http.StatusInternalServerError, "apply payment failed")
 			return
 		}
@@ -276,6 +417,10 @@ func (h *Handler) AdminListOrders(w http.ResponseWriter, r *http.Request) {
 		if order.TxHash != nil {
 			item.TxHash = *order.TxHash
 		}
+		if order.ProductID != nil {
+			item.ProductID = *order.ProductID
+			item.Quantity = order.Quantity
+		}
 		items = append(items, item)
 	}
 
@@ -321,6 +466,183 @@ func (h *Handler) AdminGetOrder(w http.ResponseWriter, r *http.Request) {
 	if order.TxHash != nil {
 		resp.TxHash = *order.TxHash
 	}
+	if order.ProductID != nil {
+		resp.ProductID = *order.ProductID
+		resp.Quantity = order.Quantity
+	}
+
+	quotes, err := h.Orders.ListOrderQuotes(r.Context(), order.OrderID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "list order quotes failed")
+		return
+	}
+	for _, q := range quotes {
+		resp.Quotes = append(resp.Quotes, orderQuoteResponse{
+			AmountPeaka:   q.AmountPeaka,
+			PriceSnapshot: json.RawMessage(q.PriceSnapshot),
+			ExpiresAt:     q.ExpiresAt.Format(time.RFC3339),
+			ReplacedAt:    q.ReplacedAt.Format(time.RFC3339),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+type adminReviewRequest struct {
+	Action string `json:"action"`
+}
+
+func (h *Handler) AdminResolveReview(w http.ResponseWriter, r *http.Request) {
+	orderID := chi.URLParam(r, "orderId")
+	if orderID == "" {
+		writeError(w, http.StatusBadRequest, "missing order id")
+		return
+	}
+	var req adminReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json body")
+		return
+	}
+	if req.Action != "approve" && req.Action != "reject" {
+		writeError(w, http.StatusBadRequest, "action must be approve or reject")
+		return
+	}
 
+	order, err := h.Orders.ResolveReview(r.Context(), orderID, req.Action == "approve")
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			writeError(w, http.StatusNotFound, "order not found")
+		case errors.Is(err, services.ErrNotInReview):
+			writeError(w, http.StatusConflict, "order is not pending review")
+		default:
+			writeError(w, http.StatusInternalServerError, "resolve review failed")
+		}
+		return
+	}
+
+	resp := adminOrderResponse{
+		OrderID:          order.OrderID,
+		UserID:           order.UserID,
+		Status:           string(order.Status),
+		AmountPeaka:      order.AmountPeaka,
+		Denom:            order.Denom,
+		RecipientAddress: order.RecipientAddress,
+		ExpiresAt:        order.ExpiresAt.Format(time.RFC3339),
+		CreditIssued:     order.CreditIssued,
+		CreatedAt:        order.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:        order.UpdatedAt.Format(time.RFC3339),
+	}
+	if order.PaidAt != nil {
+		resp.PaidAt = order.PaidAt.Format(time.RFC3339)
+	}
+	if order.TxHash != nil {
+		resp.TxHash = *order.TxHash
+	}
 	writeJSON(w, http.StatusOK, resp)
 }
+
+type revenueRowResponse struct {
+	Day          string `json:"day"`
+	FiatCurrency string `json:"fiatCurrency,omitempty"`
+	Payments     int64  `json:"payments"`
+	AmountPeaka  string `json:"amountPeaka"`
+	FiatAmount   string `json:"fiatAmount,omitempty"`
+}
+
+func (h *Handler) AdminRevenueReport(w http.ResponseWriter, r *http.Request) {
+	to := time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+	from := to.AddDate(0, 0, -30)
+	if v := r.URL.Query().Get("from"); v != "" {
+		t, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid from")
+			return
+		}
+		from = t
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		t, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid to")
+			return
+		}
+		to = t
+	}
+
+	rows, err := h.Orders.RevenueByDay(r.Context(), from, to)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "revenue report failed")
+		return
+	}
+
+	items := make([]revenueRowResponse, 0, len(rows))
+	for _, row := range rows {
+		item := revenueRowResponse{
+			Day:          row.Day.Format(time.DateOnly),
+			FiatCurrency: row.FiatCurrency,
+			Payments:     row.Payments,
+			AmountPeaka:  row.AmountPeaka,
+		}
+		if row.FiatCurrency != "" {
+			item.FiatAmount = row.FiatAmount
+		}
+		items = append(items, item)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"items": items,
+		"from":  from.Format(time.DateOnly),
+		"to":    to.Format(time.DateOnly),
+	})
+}
+
+type endpointHealthResponse struct {
+	Endpoint     string `json:"endpoint"`
+	Active       bool   `json:"active"`
+	Healthy      bool   `json:"healthy"`
+	LatestHeight int64  `json:"latestHeight"`
+	Lag          int64  `json:"lag"`
+	CatchingUp   bool   `json:"catchingUp"`
+	NodeVersion  string `json:"nodeVersion,omitempty"`
+	LatencyMs    int64  `json:"latencyMs"`
+	LastError    string `json:"lastError,omitempty"`
+	CheckedAt    string `json:"checkedAt,omitempty"`
+}
+
+func (h *Handler) AdminChainHealth(w http.ResponseWriter, r *http.Request) {
+	if h.Chain == nil {
+		writeError(w, http.StatusPreconditionFailed, "rpc client not configured")
+		return
+	}
+	reporter, ok := h.Chain.(interface{ Health() []chain.EndpointHealth })
+	if !ok {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"items": []endpointHealthResponse{{Endpoint: h.Chain.BaseURL(), Active: true}},
+		})
+		return
+	}
+
+	health := reporter.Health()
+	items := make([]endpointHealthResponse, 0, len(health))
+	for _, eh := range health {
+		item := endpointHealthResponse{
+			Endpoint:     eh.Endpoint,
+			Active:       eh.Active,
+			Healthy:      eh.Healthy,
+			LatestHeight: eh.LatestHeight,
+			Lag:          eh.Lag,
+			CatchingUp:   eh.CatchingUp,
+			NodeVersion:  eh.NodeVersion,
+			LatencyMs:    eh.Latency.Milliseconds(),
+			LastError:    eh.LastError,
+		}
+		if !eh.CheckedAt.IsZero() {
+			item.CheckedAt = eh.CheckedAt.Format(time.RFC3339)
+		}
+		items = append(items, item)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"items": items,
+	})
+}
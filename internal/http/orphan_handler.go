@@ -0,0 +1,124 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"DORAPollCredit/internal/models"
+	"DORAPollCredit/internal/services"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+type orphanPaymentResponse struct {
+	TxHash       string `json:"txHash"`
+	OrderID      string `json:"orderId"`
+	FromAddress  string `json:"fromAddress,omitempty"`
+	ToAddress    string `json:"toAddress"`
+	AmountPeaka  string `json:"amountPeaka"`
+	Denom        string `json:"denom"`
+	Height       int64  `json:"height"`
+	BlockTime    string `json:"blockTime"`
+	Status       string `json:"status"`
+	CreditIssued *int64 `json:"creditIssued,omitempty"`
+	RefundTxHash string `json:"refundTxHash,omitempty"`
+	Note         string `json:"note,omitempty"`
+	ResolvedAt   string `json:"resolvedAt,omitempty"`
+	CreatedAt    string `json:"createdAt"`
+}
+
+type resolveOrphanRequest struct {
+	Action       string `json:"action"`
+	Credit       int64  `json:"credit,omitempty"`
+	RefundTxHash string `json:"refundTxHash,omitempty"`
+	Note         string `json:"note,omitempty"`
+}
+
+func (h *Handler) AdminListOrphanPayments(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	limit := parseQueryInt(r, "limit", 50)
+	offset := parseQueryInt(r, "offset", 0)
+
+	orphans, totals, err := h.Orders.ListOrphanPayments(r.Context(), status, limit, offset)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "list orphan payments failed")
+		return
+	}
+	items := make([]orphanPaymentResponse, 0, len(orphans))
+	for _, p := range orphans {
+		items = append(items, toOrphanPaymentResponse(p))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"items": items,
+		"totals": map[string]any{
+			"count":       totals.Count,
+			"amountPeaka": totals.AmountPeaka,
+		},
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+func (h *Handler) AdminResolveOrphanPayment(w http.ResponseWriter, r *http.Request) {
+	txHash := strings.ToUpper(strings.TrimSpace(chi.URLParam(r, "txHash")))
+	if txHash == "" {
+		writeError(w, http.StatusBadRequest, "missing tx hash")
+		return
+	}
+	var req resolveOrphanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json body")
+		return
+	}
+
+	orphan, err := h.Orders.ResolveOrphanPayment(r.Context(), txHash, services.OrphanResolution{
+		Action:       req.Action,
+		Credit:       req.Credit,
+		RefundTxHash: req.RefundTxHash,
+		Note:         req.Note,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidResolution):
+			writeError(w, http.StatusBadRequest, "action must be credit with credit > 0 or refund with refundTxHash")
+		case errors.Is(err, pgx.ErrNoRows):
+			writeError(w, http.StatusNotFound, "orphan payment not found")
+		case errors.Is(err, services.ErrOrphanResolved):
+			writeError(w, http.StatusConflict, "orphan payment already resolved")
+		default:
+			writeError(w, http.StatusInternalServerError, "resolve orphan payment failed")
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, toOrphanPaymentResponse(orphan))
+}
+
+func toOrphanPaymentResponse(p *models.OrphanPayment) orphanPaymentResponse {
+	resp := orphanPaymentResponse{
+		TxHash:       p.TxHash,
+		OrderID:      p.OrderID,
+		FromAddress:  p.FromAddress,
+		ToAddress:    p.ToAddress,
+		AmountPeaka:  p.AmountPeaka,
+		Denom:        p.Denom,
+		Height:       p.Height,
+		BlockTime:    p.BlockTime.Format(time.RFC3339),
+		Status:       string(p.Status),
+		CreditIssued: p.CreditIssued,
+		CreatedAt:    p.CreatedAt.Format(time.RFC3339),
+	}
+	if p.RefundTxHash != nil {
+		resp.RefundTxHash = *p.RefundTxHash
+	}
+	if p.Note != nil {
+		resp.Note = *p.Note
+	}
+	if p.ResolvedAt != nil {
+		resp.ResolvedAt = p.ResolvedAt.Format(time.RFC3339)
+	}
+	return resp
+}
@@ -0,0 +1,197 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"DORAPollCredit/internal/models"
+	"DORAPollCredit/internal/services"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+type productRequest struct {
+	ProductID    string  `json:"productId"`
+	Name         string  `json:"name"`
+	CreditAmount int64   `json:"creditAmount"`
+	BonusCredit  int64   `json:"bonusCredit"`
+	PricePeaka   *string `json:"pricePeaka,omitempty"`
+	Active       *bool   `json:"active,omitempty"`
+	ActiveFrom   string  `json:"activeFrom,omitempty"`
+	ActiveUntil  string  `json:"activeUntil,omitempty"`
+}
+
+type productResponse struct {
+	ProductID    string  `json:"productId"`
+	Name         string  `json:"name"`
+	CreditAmount int64   `json:"creditAmount"`
+	BonusCredit  int64   `json:"bonusCredit"`
+	PricePeaka   *string `json:"pricePeaka,omitempty"`
+	Active       bool    `json:"active"`
+	ActiveFrom   string  `json:"activeFrom,omitempty"`
+	ActiveUntil  string  `json:"activeUntil,omitempty"`
+	CreatedAt    string  `json:"createdAt"`
+	UpdatedAt    string  `json:"updatedAt"`
+}
+
+func (h *Handler) ListProducts(w http.ResponseWriter, r *http.Request) {
+	products, err := h.Products.ListProducts(r.Context(), true)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "list products failed")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"items": toProductResponses(products),
+	})
+}
+
+func (h *Handler) AdminListProducts(w http.ResponseWriter, r *http.Request) {
+	products, err := h.Products.ListProducts(r.Context(), false)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "list products failed")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"items": toProductResponses(products),
+	})
+}
+
+func (h *Handler) AdminGetProduct(w http.ResponseWriter, r *http.Request) {
+	productID := chi.URLParam(r, "productId")
+	product, err := h.Products.GetProduct(r.Context(), productID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "product not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "get product failed")
+		return
+	}
+	writeJSON(w, http.StatusOK, toProductResponse(product))
+}
+
+func (h *Handler) AdminCreateProduct(w http.ResponseWriter, r *http.Request) {
+	product, ok := decodeProductRequest(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Products.CreateProduct(r.Context(), product); err != nil {
+		if errors.Is(err, services.ErrInvalidProduct) {
+			writeError(w, http.StatusBadRequest, "invalid product")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "create product failed")
+		return
+	}
+	h.writeProduct(w, r, product.ProductID)
+}
+
+func (h *Handler) AdminUpdateProduct(w http.ResponseWriter, r *http.Request) {
+	product, ok := decodeProductRequest(w, r)
+	if !ok {
+		return
+	}
+	product.ProductID = chi.URLParam(r, "productId")
+	if err := h.Products.UpdateProduct(r.Context(), product); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidProduct):
+			writeError(w, http.StatusBadRequest, "invalid product")
+		case errors.Is(err, pgx.ErrNoRows):
+			writeError(w, http.StatusNotFound, "product not found")
+		default:
+			writeError(w, http.StatusInternalServerError, "update product failed")
+		}
+		return
+	}
+	h.writeProduct(w, r, product.ProductID)
+}
+
+func (h *Handler) AdminDeleteProduct(w http.ResponseWriter, r *http.Request) {
+	productID := chi.URLParam(r, "productId")
+	if err := h.Products.DeactivateProduct(r.Context(), productID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "product not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "delete product failed")
+		return
+	}
+	h.writeProduct(w, r, productID)
+}
+
+func (h *Handler) writeProduct(w http.ResponseWriter, r *http.Request, productID string) {
+	product, err := h.Products.GetProduct(r.Context(), productID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "get product failed")
+		return
+	}
+	writeJSON(w, http.StatusOK, toProductResponse(product))
+}
+
+func decodeProductRequest(w http.ResponseWriter, r *http.Request) (*models.Product, bool) {
+	var req productRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json body")
+		return nil, false
+	}
+
+	product := &models.Product{
+		ProductID:    req.ProductID,
+		Name:         req.Name,
+		CreditAmount: req.CreditAmount,
+		BonusCredit:  req.BonusCredit,
+		PricePeaka:   req.PricePeaka,
+		Active:       true,
+	}
+	if req.Active != nil {
+		product.Active = *req.Active
+	}
+	if req.ActiveFrom != "" {
+		t, err := time.Parse(time.RFC3339, req.ActiveFrom)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid activeFrom")
+			return nil, false
+		}
+		product.ActiveFrom = &t
+	}
+	if req.ActiveUntil != "" {
+		t, err := time.Parse(time.RFC3339, req.ActiveUntil)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid activeUntil")
+			return nil, false
+		}
+		product.ActiveUntil = &t
+	}
+	return product, true
+}
+
+func toProductResponses(products []*models.Product) []productResponse {
+	items := make([]productResponse, 0, len(products))
+	for _, p := range products {
+		items = append(items, toProductResponse(p))
+	}
+	return items
+}
+
+func toProductResponse(p *models.Product) productResponse {
+	resp := productResponse{
+		ProductID:    p.ProductID,
+		Name:         p.Name,
+		CreditAmount: p.CreditAmount,
+		BonusCredit:  p.BonusCredit,
+		PricePeaka:   p.PricePeaka,
+		Active:       p.Active,
+		CreatedAt:    p.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:    p.UpdatedAt.Format(time.RFC3339),
+	}
+	if p.ActiveFrom != nil {
+		resp.ActiveFrom = p.ActiveFrom.Format(time.RFC3339)
+	}
+	if p.ActiveUntil != nil {
+		resp.ActiveUntil = p.ActiveUntil.Format(time.RFC3339)
+	}
+	return resp
+}
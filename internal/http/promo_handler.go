@@ -0,0 +1,156 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"DORAPollCredit/internal/models"
+	"DORAPollCredit/internal/services"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+type promoCodeRequest struct {
+	Code           string `json:"code"`
+	Kind           string `json:"kind"`
+	DiscountBps    int64  `json:"discountBps"`
+	BonusCredit    int64  `json:"bonusCredit"`
+	MaxRedemptions *int64 `json:"maxRedemptions,omitempty"`
+	PerUserLimit   *int64 `json:"perUserLimit,omitempty"`
+	Active         *bool  `json:"active,omitempty"`
+	ExpiresAt      string `json:"expiresAt,omitempty"`
+}
+
+type promoCodeResponse struct {
+	Code           string `json:"code"`
+	Kind           string `json:"kind"`
+	DiscountBps    int64  `json:"discountBps,omitempty"`
+	BonusCredit    int64  `json:"bonusCredit,omitempty"`
+	MaxRedemptions *int64 `json:"maxRedemptions,omitempty"`
+	PerUserLimit   *int64 `json:"perUserLimit,omitempty"`
+	RedeemedCount  int64  `json:"redeemedCount"`
+	Active         bool   `json:"active"`
+	ExpiresAt      string `json:"expiresAt,omitempty"`
+	CreatedAt      string `json:"createdAt"`
+	UpdatedAt      string `json:"updatedAt"`
+}
+
+func (h *Handler) AdminListPromoCodes(w http.ResponseWriter, r *http.Request) {
+	promos, err := h.Promos.ListPromoCodes(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "list promo codes failed")
+		return
+	}
+	items := make([]promoCodeResponse, 0, len(promos))
+	for _, p := range promos {
+		items = append(items, toPromoCodeResponse(p))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"items": items,
+	})
+}
+
+func (h *Handler) AdminGetPromoCode(w http.ResponseWriter, r *http.Request) {
+	h.writePromoCode(w, r, chi.URLParam(r, "code"))
+}
+
+func (h *Handler) AdminCreatePromoCode(w http.ResponseWriter, r *http.Request) {
+	promo, ok := decodePromoCodeRequest(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Promos.CreatePromoCode(r.Context(), promo); err != nil {
+		if errors.Is(err, services.ErrInvalidPromo) {
+			writeError(w, http.StatusBadRequest, "invalid promo code")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "create promo code failed")
+		return
+	}
+	h.writePromoCode(w, r, promo.Code)
+}
+
+func (h *Handler) AdminUpdatePromoCode(w http.ResponseWriter, r *http.Request) {
+	promo, ok := decodePromoCodeRequest(w, r)
+	if !ok {
+		return
+	}
+	promo.Code = chi.URLParam(r, "code")
+	if err := h.Promos.UpdatePromoCode(r.Context(), promo); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidPromo):
+			writeError(w, http.StatusBadRequest, "invalid promo code")
+		case errors.Is(err, pgx.ErrNoRows):
+			writeError(w, http.StatusNotFound, "promo code not found")
+		default:
+			writeError(w, http.StatusInternalServerError, "update promo code failed")
+		}
+		return
+	}
+	h.writePromoCode(w, r, promo.Code)
+}
+
+func (h *Handler) writePromoCode(w http.ResponseWriter, r *http.Request, code string) {
+	promo, err := h.Promos.GetPromoCode(r.Context(), code)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "promo code not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "get promo code failed")
+		return
+	}
+	writeJSON(w, http.StatusOK, toPromoCodeResponse(promo))
+}
+
+func decodePromoCodeRequest(w http.ResponseWriter, r *http.Request) (*models.PromoCode, bool) {
+	var req promoCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json body")
+		return nil, false
+	}
+
+	promo := &models.PromoCode{
+		Code:           req.Code,
+		Kind:           models.PromoKind(req.Kind),
+		DiscountBps:    req.DiscountBps,
+		BonusCredit:    req.BonusCredit,
+		MaxRedemptions: req.MaxRedemptions,
+		PerUserLimit:   req.PerUserLimit,
+		Active:         true,
+	}
+	if req.Active != nil {
+		promo.Active = *req.Active
+	}
+	if req.ExpiresAt != "" {
+		t, err := time.Parse(time.RFC3339, req.ExpiresAt)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid expiresAt")
+			return nil, false
+		}
+		promo.ExpiresAt = &t
+	}
+	return promo, true
+}
+
+func toPromoCodeResponse(p *models.PromoCode) promoCodeResponse {
+	resp := promoCodeResponse{
+		Code:           p.Code,
+		Kind:           string(p.Kind),
+		DiscountBps:    p.DiscountBps,
+		BonusCredit:    p.BonusCredit,
+		MaxRedemptions: p.MaxRedemptions,
+		PerUserLimit:   p.PerUserLimit,
+		RedeemedCount:  p.RedeemedCount,
+		Active:         p.Active,
+		CreatedAt:      p.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:      p.UpdatedAt.Format(time.RFC3339),
+	}
+	if p.ExpiresAt != nil {
+		resp.ExpiresAt = p.ExpiresAt.Format(time.RFC3339)
+	}
+	return resp
+}
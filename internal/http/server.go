@@ -24,15 +24,35 @@ func NewServer(handler *Handler) *Server {
 	})
 
 	r.Route("/payments", func(r chi.Router) {
+		r.Get("/quote", handler.GetQuote)
+		r.Get("/products", handler.ListProducts)
 		r.Post("/orders", handler.CreateOrder)
 		r.Get("/orders/{orderId}", handler.GetOrder)
+		r.Post("/orders/{orderId}/requote", handler.RequoteOrder)
 		r.Post("/confirm", handler.ConfirmPayment)
 	})
 
 	r.Route("/admin", func(r chi.Router) {
 		r.Get("/orders", handler.AdminListOrders)
 		r.Get("/orders/{orderId}", handler.AdminGetOrder)
+		r.Post("/orders/{orderId}/review", handler.AdminResolveReview)
 		r.Post("/verify-tx", handler.AdminVerifyTx)
+		r.Get("/orphan-payments", handler.AdminListOrphanPayments)
+		r.Post("/orphan-payments/{txHash}/resolve", handler.AdminResolveOrphanPayment)
+		r.Get("/unmatched-payments", handler.AdminListUnmatchedPayments)
+		r.Get("/unmatched-payments/{id}", handler.AdminGetUnmatchedPayment)
+		r.Post("/unmatched-payments/{id}/resolve", handler.AdminResolveUnmatchedPayment)
+		r.Get("/reports/revenue", handler.AdminRevenueReport)
+		r.Get("/chain/health", handler.AdminChainHealth)
+		r.Get("/products", handler.AdminListProducts)
+		r.Post("/products", handler.AdminCreateProduct)
+		r.Get("/products/{productId}", handler.AdminGetProduct)
+		r.Put("/products/{productId}", handler.AdminUpdateProduct)
+		r.Delete("/products/{productId}", handler.AdminDeleteProduct)
+		r.Get("/promos", handler.AdminListPromoCodes)
+		r.Post("/promos", handler.AdminCreatePromoCode)
+		r.Get("/promos/{code}", handler.AdminGetPromoCode)
+		r.Put("/promos/{code}", handler.AdminUpdatePromoCode)
 	})
 
 	return &Server{Router: r}
@@ -0,0 +1,185 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"DORAPollCredit/internal/chain"
+	"DORAPollCredit/internal/models"
+	"DORAPollCredit/internal/services"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+type unmatchedPaymentResponse struct {
+	ID              int64  `json:"id"`
+	TxHash          string `json:"txHash"`
+	ToAddress       string `json:"toAddress"`
+	DerivationIndex int64  `json:"derivationIndex"`
+	OrderID         string `json:"orderId,omitempty"`
+	FromAddress     string `json:"fromAddress,omitempty"`
+	AmountPeaka     string `json:"amountPeaka"`
+	Denom           string `json:"denom"`
+	Height          int64  `json:"height"`
+	BlockTime       string `json:"blockTime"`
+	Reason          string `json:"reason"`
+	Status          string `json:"status"`
+	ResolvedOrderID string `json:"resolvedOrderId,omitempty"`
+	Note            string `json:"note,omitempty"`
+	ResolvedAt      string `json:"resolvedAt,omitempty"`
+	CreatedAt       string `json:"createdAt"`
+}
+
+type resolveUnmatchedRequest struct {
+	Action  string `json:"action"`
+	OrderID string `json:"orderId,omitempty"`
+	Note    string `json:"note,omitempty"`
+}
+
+func (h *Handler) AdminListUnmatchedPayments(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	limit := parseQueryInt(r, "limit", 50)
+	offset := parseQueryInt(r, "offset", 0)
+
+	unmatched, err := h.Orders.ListUnmatchedPayments(r.Context(), status, limit, offset)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "list unmatched payments failed")
+		return
+	}
+	items := make([]unmatchedPaymentResponse, 0, len(unmatched))
+	for _, p := range unmatched {
+		items = append(items, toUnmatchedPaymentResponse(p))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"items":  items,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+func (h *Handler) AdminGetUnmatchedPayment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	p, err := h.Orders.GetUnmatchedPayment(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "unmatched payment not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "get unmatched payment failed")
+		return
+	}
+	writeJSON(w, http.StatusOK, toUnmatchedPaymentResponse(p))
+}
+
+// AdminResolveUnmatchedPayment applies an unmatched payment to an order
+// ({"action":"apply","orderId":"..."}) after re-fetching the tx, or dismisses
+// it ({"action":"dismiss"}).
+func (h *Handler) AdminResolveUnmatchedPayment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var req resolveUnmatchedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json body")
+		return
+	}
+
+	var p *models.UnmatchedPayment
+	switch req.Action {
+	case "apply":
+		if req.OrderID == "" {
+			writeError(w, http.StatusBadRequest, "missing orderId")
+			return
+		}
+		if h.Chain == nil {
+			writeError(w, http.StatusPreconditionFailed, "rpc client not configured")
+			return
+		}
+		current, err := h.Orders.GetUnmatchedPayment(r.Context(), id)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				writeError(w, http.StatusNotFound, "unmatched payment not found")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "get unmatched payment failed")
+			return
+		}
+		tx, err := h.Chain.TxByHash(r.Context(), current.TxHash)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "tx query failed")
+			return
+		}
+		p, err = h.Orders.ApplyUnmatchedPayment(r.Context(), id, req.OrderID, tx, req.Note)
+		if err != nil {
+			writeUnmatchedError(w, err)
+			return
+		}
+	case "dismiss":
+		p, err = h.Orders.DismissUnmatchedPayment(r.Context(), id, req.Note)
+		if err != nil {
+			writeUnmatchedError(w, err)
+			return
+		}
+	default:
+		writeError(w, http.StatusBadRequest, "action must be apply or dismiss")
+		return
+	}
+	writeJSON(w, http.StatusOK, toUnmatchedPaymentResponse(p))
+}
+
+func writeUnmatchedError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		writeError(w, http.StatusNotFound, "unmatched payment or order not found")
+	case errors.Is(err, services.ErrUnmatchedResolved):
+		writeError(w, http.StatusConflict, "unmatched payment already resolved")
+	case errors.Is(err, services.ErrOrderNotPayable):
+		writeError(w, http.StatusConflict, "order cannot take a payment")
+	case errors.Is(err, services.ErrTransferNotFound):
+		writeError(w, http.StatusUnprocessableEntity, "tx does not contain the recorded transfer")
+	case errors.Is(err, chain.ErrUnproven):
+		writeError(w, http.StatusUnprocessableEntity, "tx inclusion not proven")
+	default:
+		writeError(w, http.StatusInternalServerError, "resolve unmatched payment failed")
+	}
+}
+
+func toUnmatchedPaymentResponse(p *models.UnmatchedPayment) unmatchedPaymentResponse {
+	resp := unmatchedPaymentResponse{
+		ID:              p.ID,
+		TxHash:          p.TxHash,
+		ToAddress:       p.ToAddress,
+		DerivationIndex: p.DerivationIndex,
+		FromAddress:     p.FromAddress,
+		AmountPeaka:     p.AmountPeaka,
+		Denom:           p.Denom,
+		Height:          p.Height,
+		BlockTime:       p.BlockTime.Format(time.RFC3339),
+		Reason:          p.Reason,
+		Status:          string(p.Status),
+		CreatedAt:       p.CreatedAt.Format(time.RFC3339),
+	}
+	if p.OrderID != nil {
+		resp.OrderID = *p.OrderID
+	}
+	if p.ResolvedOrderID != nil {
+		resp.ResolvedOrderID = *p.ResolvedOrderID
+	}
+	if p.Note != nil {
+		resp.Note = *p.Note
+	}
+	if p.ResolvedAt != nil {
+		resp.ResolvedAt = p.ResolvedAt.Format(time.RFC3339)
+	}
+	return resp
+}
@@ -12,8 +12,27 @@ const (
 	OrderLateNoCredit    OrderStatus = "late_no_credit"
 	OrderUnderpaid       OrderStatus = "underpaid"
 	OrderOverpaid        OrderStatus = "overpaid"
+	OrderPendingReview   OrderStatus = "pending_review"
+	OrderReviewRejected  OrderStatus = "review_rejected"
 )
 
+// orderTransitions lists the status changes an order may go through.
+// expired -> created is only taken by a requote of an unpaid order.
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderCreated:       {OrderPaid, OrderExpired, OrderLateNoCredit, OrderUnderpaid, OrderOverpaid, OrderPendingReview},
+	OrderExpired:       {OrderCreated, OrderPaid, OrderPaidLateReprice, OrderLateNoCredit, OrderUnderpaid, OrderOverpaid, OrderPendingReview},
+	OrderPendingReview: {OrderPaid, OrderPaidLateReprice, OrderLateNoCredit, OrderUnderpaid, OrderOverpaid, OrderReviewRejected},
+}
+
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, st := range orderTransitions[s] {
+		if st == next {
+			return true
+		}
+	}
+	return false
+}
+
 type Order struct {
 	OrderID          string
 	UserID           string
@@ -28,18 +47,157 @@ type Order struct {
 	PaidAt           *time.Time
 	TxHash           *string
 	CreditIssued     *int64
-	CreatedAt        time.Time
-	UpdatedAt        time.Time
+	ProductID        *string
+	Quantity         *int64
+	ProductSnapshot  *string
+	PromoCode        *string
+	// CreatedHeight is the chain height when the order was created, if known.
+	// ScannedHeight is the last height searched for payments to it.
+	CreatedHeight *int64
+	ScannedHeight *int64
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+type Product struct {
+	ProductID    string
+	Name         string
+	CreditAmount int64
+	BonusCredit  int64
+	PricePeaka   *string
+	Active       bool
+	ActiveFrom   *time.Time
+	ActiveUntil  *time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+type OrderQuote struct {
+	OrderID       string
+	AmountPeaka   string
+	PriceSnapshot string
+	ExpiresAt     time.Time
+	ReplacedAt    time.Time
 }
 
 type Payment struct {
-	TxHash      string
-	OrderID     string
-	FromAddress string
-	ToAddress   string
-	AmountPeaka string
-	Denom       string
-	Height      int64
-	BlockTime   time.Time
-	CreatedAt   time.Time
+	TxHash       string
+	OrderID      string
+	FromAddress  string
+	ToAddress    string
+	AmountPeaka  string
+	Denom        string
+	Height       int64
+	BlockTime    time.Time
+	FiatCurrency *string
+	FiatRate     *string
+	FiatAmount   *string
+	ReviewReason *string
+	CreatedAt    time.Time
+}
+
+type OrphanStatus string
+
+const (
+	OrphanOpen     OrphanStatus = "open"
+	OrphanCredited OrphanStatus = "credited"
+	OrphanRefunded OrphanStatus = "refunded"
+)
+
+// OrphanPayment is a transfer to the address of an order that was already
+// settled by another tx.
+type OrphanPayment struct {
+	TxHash       string
+	OrderID      string
+	FromAddress  string
+	ToAddress    string
+	AmountPeaka  string
+	Denom        string
+	Height       int64
+	BlockTime    time.Time
+	Status       OrphanStatus
+	CreditIssued *int64
+	RefundTxHash *string
+	Note         *string
+	ResolvedAt   *time.Time
+	CreatedAt    time.Time
+}
+
+type UnmatchedStatus string
+
+const (
+	UnmatchedOpen      UnmatchedStatus = "open"
+	UnmatchedApplied   UnmatchedStatus = "applied"
+	UnmatchedDismissed UnmatchedStatus = "dismissed"
+)
+
+// UnmatchedPayment is a transfer to one of our derived addresses that no
+// watched order could take. OrderID is the order holding the address, if
+// any; Reason says why it was not applied.
+type UnmatchedPayment struct {
+	ID              int64
+	TxHash          string
+	ToAddress       string
+	DerivationIndex int64
+	OrderID         *string
+	FromAddress     string
+	AmountPeaka     string
+	Denom           string
+	Height          int64
+	BlockTime       time.Time
+	Reason          string
+	Status          UnmatchedStatus
+	ResolvedOrderID *string
+	Note            *string
+	ResolvedAt      *time.Time
+	CreatedAt       time.Time
+}
+
+// SeenPayment is a tx seen on the WebSocket stream that has not yet reached
+// the confirmation depth. OrderID is empty for a transfer to a derived
+// address that no watched order takes.
+type SeenPayment struct {
+	TxHash    string
+	OrderID   string
+	ToAddress string
+	Height    int64
+	SeenAt    time.Time
+}
+
+type RevenueRow struct {
+	Day          time.Time
+	FiatCurrency string
+	Payments     int64
+	AmountPeaka  string
+	FiatAmount   string
+}
+
+type PromoKind string
+
+const (
+	PromoPercent PromoKind = "percent"
+	PromoBonus   PromoKind = "bonus"
+)
+
+type PromoCode struct {
+	Code           string
+	Kind           PromoKind
+	DiscountBps    int64
+	BonusCredit    int64
+	MaxRedemptions *int64
+	PerUserLimit   *int64
+	RedeemedCount  int64
+	Active         bool
+	ExpiresAt      *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// CapReached reports whether the code has no redemptions left, overall or
+// for a user who has already redeemed it userRedemptions times.
+func (p *PromoCode) CapReached(userRedemptions int64) bool {
+	if p.MaxRedemptions != nil && p.RedeemedCount >= *p.MaxRedemptions {
+		return true
+	}
+	return p.PerUserLimit != nil && userRedemptions >= *p.PerUserLimit
 }
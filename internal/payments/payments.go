@@ -2,12 +2,14 @@ package payments
 
 import (
 	"context"
+	"errors"
 	"math/big"
 	"strings"
 	"time"
 
 	"DORAPollCredit/internal/chain"
 	"DORAPollCredit/internal/models"
+	"DORAPollCredit/internal/pricing"
 	"DORAPollCredit/internal/store"
 )
 
@@ -61,30 +63,22 @@ func ExtractTransfers(events []chain.Event, denom string) []Transfer {
 	return out
 }
 
-func ApplyPayment(ctx context.Context, st *store.Store, order *models.Order, tx chain.Tx, amount string, sender string) (models.OrderStatus, bool, error) {
-	paidAt := tx.Timestamp
+// ApplyPayment records the payment and settles the order. val is the fiat
+// value of amount at payment time and may be nil. When v has a Prover the tx
+// must be proven against light-client verified headers first, and the proven
+// block time becomes paidAt. When v.Quorum applies to amount the tx must also
+// be confirmed by a quorum of endpoints. If the endpoints disagree, the proven
+// tx does not carry the payment, or the order's promo code has run out, the
+// payment is recorded and the order parked in pending_review.
+func ApplyPayment(ctx context.Context, st *store.Store, order *models.Order, tx chain.Tx, amount string, sender string, val *pricing.Valuation, v Verification) (models.OrderStatus, bool, error) {
+	paidAt, reviewReason, err := v.Check(ctx, tx, order.RecipientAddress, amount)
+	if err != nil {
+		return order.Status, false, err
+	}
 	if paidAt.IsZero() {
 		paidAt = time.Now().UTC()
 	}
 
-	cmp := CompareAmount(amount, order.AmountPeaka)
-	status := models.OrderPaid
-	var creditIssued *int64
-
-	switch {
-	case cmp < 0:
-		status = models.OrderUnderpaid
-	case cmp > 0:
-		status = models.OrderOverpaid
-	default:
-		if paidAt.After(order.ExpiresAt) {
-			status = models.OrderLateNoCredit
-		} else {
-			status = models.OrderPaid
-			creditIssued = &order.CreditRequested
-		}
-	}
-
 	payment := &models.Payment{
 		TxHash:      tx.Hash,
 		OrderID:     order.OrderID,
@@ -95,17 +89,54 @@ func ApplyPayment(ctx context.Context, st *store.Store, order *models.Order, tx
 		Height:      tx.Height,
 		BlockTime:   paidAt,
 	}
+	if val != nil {
+		payment.FiatCurrency = &val.Currency
+		payment.FiatRate = &val.Rate
+		payment.FiatAmount = &val.Amount
+	}
+
+	if reviewReason != "" {
+		payment.ReviewReason = &reviewReason
+		if err := st.InsertPayment(ctx, payment); err != nil {
+			return models.OrderPendingReview, false, err
+		}
+		updated, err := st.MarkPendingReview(ctx, order.OrderID, paidAt, tx.Hash)
+		if err != nil {
+			return models.OrderPendingReview, false, err
+		}
+		return models.OrderPendingReview, updated > 0, nil
+	}
+
+	status, creditIssued := SettlementStatus(order, amount, paidAt)
 	if err := st.InsertPayment(ctx, payment); err != nil {
 		return status, false, err
 	}
 
 	updated, err := st.UpdateOrderPayment(ctx, order.OrderID, status, paidAt, tx.Hash, creditIssued)
+	if errors.Is(err, store.ErrPromoExhausted) {
+		return models.OrderPendingReview, true, nil
+	}
 	if err != nil {
 		return status, false, err
 	}
 	return status, updated > 0, nil
 }
 
+// SettlementStatus decides the order status and issued credit for a payment
+// of amount received at paidAt.
+func SettlementStatus(order *models.Order, amount string, paidAt time.Time) (models.OrderStatus, *int64) {
+	switch cmp := CompareAmount(amount, order.AmountPeaka); {
+	case cmp < 0:
+		return models.OrderUnderpaid, nil
+	case cmp > 0:
+		return models.OrderOverpaid, nil
+	case paidAt.After(order.ExpiresAt):
+		return models.OrderLateNoCredit, nil
+	default:
+		return models.OrderPaid, &order.CreditRequested
+	}
+}
+
 func CompareAmount(a, b string) int {
 	ai, ok1 := new(big.Int).SetString(a, 10)
 	bi, ok2 := new(big.Int).SetString(b, 10)
@@ -0,0 +1,78 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"DORAPollCredit/internal/chain"
+)
+
+// Prover is implemented by chain.ProofVerifier.
+type Prover interface {
+	ProveTx(ctx context.Context, hash string, height int64) (*chain.ProvenTx, error)
+}
+
+// Verification holds the optional checks a payment must pass before it is
+// settled. The zero value settles on the node's word alone.
+type Verification struct {
+	Quorum *Quorum
+	Prover Prover
+	Denom  string
+}
+
+// Check runs the configured checks on a transfer of amount to recipient in tx.
+// It returns the block time to settle with, which is zero unless proven or
+// set on tx, and a non-empty review reason when the payment must be parked in
+// pending_review. An error means the checks could not run; the payment must
+// be left unsettled and retried.
+func (v Verification) Check(ctx context.Context, tx chain.Tx, recipient, amount string) (time.Time, string, error) {
+	paidAt := tx.Timestamp
+	if v.Prover != nil {
+		proven, err := v.prove(ctx, tx, recipient, amount)
+		var reviewErr *ReviewError
+		switch {
+		case errors.As(err, &reviewErr):
+			return proven.Time.UTC(), reviewErr.Reason, nil
+		case err != nil:
+			return time.Time{}, "", err
+		}
+		paidAt = proven.Time.UTC()
+	}
+	if q := v.Quorum; q.applies(amount) {
+		err := q.Check(ctx, tx, recipient, amount)
+		var reviewErr *ReviewError
+		switch {
+		case errors.As(err, &reviewErr):
+			return paidAt, reviewErr.Reason, nil
+		case err != nil:
+			return time.Time{}, "", err
+		}
+	}
+	return paidAt, "", nil
+}
+
+// prove returns the block time of tx once its inclusion, success and transfer
+// of amount to recipient are proven. A proven tx that failed or does not carry
+// the transfer returns a *ReviewError, since retrying cannot change it; any
+// other failure is transient and leaves the order untouched so the payment is
+// retried on the next scan.
+func (v Verification) prove(ctx context.Context, tx chain.Tx, recipient, amount string) (*chain.ProvenTx, error) {
+	proven, err := v.Prover.ProveTx(ctx, tx.Hash, tx.Height)
+	if err != nil {
+		return nil, err
+	}
+	if proven.Code != 0 {
+		return proven, &ReviewError{Reason: fmt.Sprintf("proven tx %s failed with code %d", tx.Hash, proven.Code)}
+	}
+	for _, s := range proven.Sends {
+		if s.Recipient != recipient {
+			continue
+		}
+		if paid, ok := parseAmountForDenom(s.Amount, v.Denom); ok && CompareAmount(paid, amount) == 0 {
+			return proven, nil
+		}
+	}
+	return proven, &ReviewError{Reason: fmt.Sprintf("proven tx %s does not send %s to %s", tx.Hash, amount, recipient)}
+}
@@ -0,0 +1,74 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"DORAPollCredit/internal/chain"
+)
+
+// QuorumVerifier is implemented by chain.MultiRPCClient.
+type QuorumVerifier interface {
+	TxByHashQuorum(ctx context.Context, hash string, k int) ([]chain.TxObservation, error)
+}
+
+// Quorum requires payments of at least MinAmount to be confirmed by Size
+// distinct endpoints before they are settled.
+type Quorum struct {
+	Verifier  QuorumVerifier
+	MinAmount string
+	Size      int
+	Denom     string
+}
+
+// ReviewError means the endpoints disagreed about the tx, or its proof did not
+// match the payment; the payment is parked for manual review instead of being
+// settled.
+type ReviewError struct {
+	Reason string
+}
+
+func (e *ReviewError) Error() string { return "payment needs review: " + e.Reason }
+
+func (q *Quorum) applies(amount string) bool {
+	if q == nil || q.Verifier == nil || q.Size < 2 {
+		return false
+	}
+	return CompareAmount(amount, q.MinAmount) >= 0
+}
+
+// Check returns nil when every observation matches tx and a *ReviewError on
+// disagreement, even among fewer than Size endpoints. When too few endpoints
+// answered it returns chain.ErrQuorumUnavailable, which is transient: the
+// payment is left unsettled and retried on the next scan.
+func (q *Quorum) Check(ctx context.Context, tx chain.Tx, recipient, amount string) error {
+	observations, err := q.Verifier.TxByHashQuorum(ctx, tx.Hash, q.Size)
+	if err != nil && !errors.Is(err, chain.ErrQuorumUnavailable) {
+		return err
+	}
+	for _, obs := range observations {
+		if obs.Tx.Height != tx.Height {
+			return &ReviewError{Reason: fmt.Sprintf("%s reports height %d, expected %d", obs.Endpoint, obs.Tx.Height, tx.Height)}
+		}
+		if obs.Tx.Code != tx.Code {
+			return &ReviewError{Reason: fmt.Sprintf("%s reports code %d, expected %d", obs.Endpoint, obs.Tx.Code, tx.Code)}
+		}
+		if !hasTransfer(obs.Tx.Events, q.Denom, recipient, amount) {
+			return &ReviewError{Reason: fmt.Sprintf("%s does not report transfer of %s to %s", obs.Endpoint, amount, recipient)}
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("tx %s: %d of %d endpoints answered: %w", tx.Hash, len(observations), q.Size, err)
+	}
+	return nil
+}
+
+func hasTransfer(events []chain.Event, denom, recipient, amount string) bool {
+	for _, t := range ExtractTransfers(events, denom) {
+		if t.Recipient == recipient && CompareAmount(t.Amount, amount) == 0 {
+			return true
+		}
+	}
+	return false
+}
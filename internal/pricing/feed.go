@@ -0,0 +1,134 @@
+package pricing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Feed returns the price of one DORA in a fiat currency as a decimal string.
+type Feed interface {
+	DoraPrice(ctx context.Context) (string, error)
+}
+
+// NewFeed returns an HTTPFeed when url is set, otherwise a StaticFeed.
+func NewFeed(url, path, staticPrice string, ttl time.Duration) Feed {
+	if url != "" {
+		return NewHTTPFeed(url, path, ttl)
+	}
+	return StaticFeed{Price: staticPrice}
+}
+
+// StaticFeed always returns Price. Useful for testnets and local runs.
+type StaticFeed struct {
+	Price string
+}
+
+func (f StaticFeed) DoraPrice(ctx context.Context) (string, error) {
+	if f.Price == "" {
+		return "", errors.New("static dora price is empty")
+	}
+	return f.Price, nil
+}
+
+// HTTPFeed reads a JSON document from URL and picks the value at Path
+// (dot separated, e.g. "dora-factory.usd" for a CoinGecko simple/price call).
+// Results are cached for TTL.
+type HTTPFeed struct {
+	URL    string
+	Path   string
+	TTL    time.Duration
+	client *http.Client
+
+	mu        sync.Mutex
+	price     string
+	fetchedAt time.Time
+}
+
+func NewHTTPFeed(url, path string, ttl time.Duration) *HTTPFeed {
+	return &HTTPFeed{
+		URL:    url,
+		Path:   path,
+		TTL:    ttl,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// DoraPrice fetches without holding the lock, so a slow feed never blocks
+// callers that could be served from the cache. Concurrent misses may each
+// fetch; the last one to finish refreshes the cache.
+func (f *HTTPFeed) DoraPrice(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	if f.price != "" && time.Since(f.fetchedAt) < f.TTL {
+		price := f.price
+		f.mu.Unlock()
+		return price, nil
+	}
+	f.mu.Unlock()
+
+	price, err := f.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	f.mu.Lock()
+	f.price = price
+	f.fetchedAt = time.Now()
+	f.mu.Unlock()
+	return price, nil
+}
+
+func (f *HTTPFeed) fetch(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.URL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("price feed http status %d", resp.StatusCode)
+	}
+
+	dec := json.NewDecoder(resp.Body)
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return "", err
+	}
+	for _, key := range strings.Split(f.Path, ".") {
+		if key == "" {
+			continue
+		}
+		obj, ok := doc.(map[string]any)
+		if !ok {
+			return "", fmt.Errorf("price feed path %q not found", f.Path)
+		}
+		doc, ok = obj[key]
+		if !ok {
+			return "", fmt.Errorf("price feed path %q not found", f.Path)
+		}
+	}
+
+	var price string
+	switch v := doc.(type) {
+	case json.Number:
+		price = v.String()
+	case string:
+		price = v
+	default:
+		return "", fmt.Errorf("price feed path %q is not a number", f.Path)
+	}
+	r, ok := new(big.Rat).SetString(price)
+	if !ok || r.Sign() <= 0 {
+		return "", fmt.Errorf("price feed returned invalid price %q", price)
+	}
+	return price, nil
+}
@@ -1,19 +1,166 @@
 package pricing
 
-import "context"
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
 
 type Service struct {
 	FixedCreditPerDora int64
+	Tiers              []Tier
+	Fiat               *FiatConfig
+}
+
+// FiatConfig prices credits in a fiat currency and converts through Feed.
+type FiatConfig struct {
+	Currency    string
+	CreditPrice string
+	Feed        Feed
+}
+
+// Tier discounts orders whose credit amount reaches MinCredit.
+type Tier struct {
+	MinCredit   int64 `json:"min_credit"`
+	DiscountBps int64 `json:"discount_bps"`
+}
+
+type Promo struct {
+	Code        string `json:"code"`
+	Kind        string `json:"kind"`
+	DiscountBps int64  `json:"discount_bps,omitempty"`
+	BonusCredit int64  `json:"bonus_credit,omitempty"`
+}
+
+// FiatRate records the fiat credit price and the DORA/fiat conversion used.
+type FiatRate struct {
+	Currency    string    `json:"currency"`
+	CreditPrice string    `json:"credit_price"`
+	DoraPrice   string    `json:"dora_price"`
+	FetchedAt   time.Time `json:"fetched_at"`
 }
 
 type Snapshot struct {
-	CreditPerDora int64  `json:"credit_per_dora"`
-	Source        string `json:"source"`
+	// CreditPerDora is a decimal: in fiat mode one DORA may buy a fraction
+	// of a credit. Snapshots stored as JSON integers still decode.
+	CreditPerDora json.Number `json:"credit_per_dora"`
+	Source        string      `json:"source"`
+	Fiat          *FiatRate   `json:"fiat,omitempty"`
+	Tier          *Tier       `json:"tier,omitempty"`
+	Promo         *Promo      `json:"promo,omitempty"`
+}
+
+// Valuation is the fiat value of an on-chain amount at a point in time.
+type Valuation struct {
+	Currency string
+	Rate     string
+	Amount   string
 }
 
 func (s Service) CurrentSnapshot(ctx context.Context) (Snapshot, error) {
+	if s.Fiat == nil {
+		return Snapshot{
+			CreditPerDora: json.Number(strconv.FormatInt(s.FixedCreditPerDora, 10)),
+			Source:        "fixed",
+		}, nil
+	}
+
+	rate, err := s.FiatRate(ctx)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	creditPrice, _ := new(big.Rat).SetString(rate.CreditPrice)
+	doraPrice, _ := new(big.Rat).SetString(rate.DoraPrice)
+	perDora := new(big.Rat).Quo(doraPrice, creditPrice)
 	return Snapshot{
-		CreditPerDora: s.FixedCreditPerDora,
-		Source:        "fixed",
+		CreditPerDora: json.Number(decimalString(perDora)),
+		Source:        "fiat",
+		Fiat:          rate,
 	}, nil
 }
+
+// FiatRate returns nil when fiat pricing is not configured.
+func (s Service) FiatRate(ctx context.Context) (*FiatRate, error) {
+	if s.Fiat == nil {
+		return nil, nil
+	}
+	creditPrice, ok := new(big.Rat).SetString(s.Fiat.CreditPrice)
+	if !ok || creditPrice.Sign() <= 0 {
+		return nil, errors.New("fiat credit price must be positive")
+	}
+	price, err := s.Fiat.Feed.DoraPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	doraPrice, ok := new(big.Rat).SetString(price)
+	if !ok || doraPrice.Sign() <= 0 {
+		return nil, errors.New("dora price must be positive")
+	}
+	return &FiatRate{
+		Currency:    s.Fiat.Currency,
+		CreditPrice: s.Fiat.CreditPrice,
+		DoraPrice:   price,
+		FetchedAt:   time.Now().UTC(),
+	}, nil
+}
+
+// Valuate converts a base-unit amount into fiat at the DORA price locked in
+// the order's price snapshot, so a payment is valued at the rate it was
+// quoted at however late it is processed. A snapshot without a fiat rate,
+// from an order priced before fiat mode was enabled, uses the current feed.
+func (s Service) Valuate(ctx context.Context, priceSnapshot, amount string, decimals int) (*Valuation, error) {
+	var snap Snapshot
+	var rate *FiatRate
+	if priceSnapshot != "" && json.Unmarshal([]byte(priceSnapshot), &snap) == nil {
+		rate = snap.Fiat
+	}
+	if rate == nil {
+		var err error
+		if rate, err = s.FiatRate(ctx); err != nil || rate == nil {
+			return nil, err
+		}
+	}
+	v, ok := new(big.Rat).SetString(amount)
+	if !ok {
+		return nil, errors.New("invalid amount")
+	}
+	doraPrice, ok := new(big.Rat).SetString(rate.DoraPrice)
+	if !ok || doraPrice.Sign() <= 0 {
+		return nil, errors.New("dora price must be positive")
+	}
+	pow := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	v.Quo(v, new(big.Rat).SetInt(pow))
+	v.Mul(v, doraPrice)
+	return &Valuation{
+		Currency: rate.Currency,
+		Rate:     rate.DoraPrice,
+		Amount:   v.FloatString(6),
+	}, nil
+}
+
+// decimalString formats r with up to 18 fractional digits, trimming
+// trailing zeros.
+func decimalString(r *big.Rat) string {
+	s := r.FloatString(18)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// TierFor returns the highest tier reached by credit, or nil.
+func (s Service) TierFor(credit int64) *Tier {
+	var best *Tier
+	for i := range s.Tiers {
+		t := s.Tiers[i]
+		if credit < t.MinCredit || t.DiscountBps <= 0 {
+			continue
+		}
+		if best == nil || t.MinCredit > best.MinCredit {
+			best = &t
+		}
+	}
+	return best
+}
@@ -0,0 +1,76 @@
+package pricing
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestValuateUsesLockedRate(t *testing.T) {
+	s := Service{Fiat: &FiatConfig{Currency: "USD", CreditPrice: "0.01", Feed: StaticFeed{Price: "0.50"}}}
+	locked, err := json.Marshal(Snapshot{
+		CreditPerDora: "8",
+		Source:        "fiat",
+		Fiat:          &FiatRate{Currency: "USD", CreditPrice: "0.01", DoraPrice: "0.08"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		snapshot string
+		rate     string
+		amount   string
+	}{
+		{name: "locked fiat rate", snapshot: string(locked), rate: "0.08", amount: "0.200000"},
+		{name: "fixed-price snapshot", snapshot: `{"credit_per_dora":100,"source":"fixed"}`, rate: "0.50", amount: "1.250000"},
+		{name: "no snapshot", snapshot: "", rate: "0.50", amount: "1.250000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			val, err := s.Valuate(context.Background(), tt.snapshot, "2500000", 6)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if val.Rate != tt.rate || val.Amount != tt.amount || val.Currency != "USD" {
+				t.Errorf("valuation = %+v, want rate %s amount %s", val, tt.rate, tt.amount)
+			}
+		})
+	}
+}
+
+func TestValuateWithoutFiat(t *testing.T) {
+	val, err := Service{FixedCreditPerDora: 100}.Valuate(context.Background(), `{"credit_per_dora":100,"source":"fixed"}`, "2500000", 6)
+	if err != nil || val != nil {
+		t.Fatalf("Valuate = %+v, %v, want nil", val, err)
+	}
+}
+
+func TestCurrentSnapshotCreditPerDora(t *testing.T) {
+	tests := []struct {
+		name string
+		s    Service
+		want json.Number
+	}{
+		{name: "fixed", s: Service{FixedCreditPerDora: 100}, want: "100"},
+		{name: "fiat whole", s: Service{Fiat: &FiatConfig{CreditPrice: "0.01", Feed: StaticFeed{Price: "0.08"}}}, want: "8"},
+		{name: "fiat fraction", s: Service{Fiat: &FiatConfig{CreditPrice: "2.5", Feed: StaticFeed{Price: "0.08"}}}, want: "0.032"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snap, err := tt.s.CurrentSnapshot(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if snap.CreditPerDora != tt.want {
+				t.Errorf("credit_per_dora = %q, want %q", snap.CreditPerDora, tt.want)
+			}
+			var decoded Snapshot
+			b, _ := json.Marshal(snap)
+			if err := json.Unmarshal(b, &decoded); err != nil || decoded.CreditPerDora != tt.want {
+				t.Errorf("round trip %s = %q, %v", b, decoded.CreditPerDora, err)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"log"
 	"math/big"
 	"time"
 
@@ -14,43 +15,139 @@ import (
 	"DORAPollCredit/internal/store"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
 var (
 	ErrMissingUserID     = errors.New("missing user id")
 	ErrInvalidCredit     = errors.New("credit below minimum")
 	ErrXpubNotConfigured = errors.New("wallet xpub not configured")
+	ErrOrderNotOwned     = errors.New("order belongs to another user")
+	ErrNotRequotable     = errors.New("order cannot be requoted")
+	ErrNotInReview       = errors.New("order is not pending review")
 )
 
 type OrderService struct {
-	Store     *store.Store
-	Deriver   chain.AddressDeriver
-	Pricing   pricing.Service
-	MinCredit int64
-	TTL       time.Duration
-	Denom     string
-	Decimals  int
+	Store       *store.Store
+	Deriver     chain.AddressDeriver
+	Pricing     pricing.Service
+	MinCredit   int64
+	TTL         time.Duration
+	Denom       string
+	Decimals    int
+	QuoteSecret string
+	QuoteTTL    time.Duration
+	Verify      payments.Verification
+	// Chain, when set, stamps new orders with the current height so the
+	// worker scans them from there.
+	Chain chain.Client
 }
 
-func (s OrderService) CreateOrder(ctx context.Context, userID string, credit int64) (*models.Order, error) {
-	if userID == "" {
+type CreateOrderParams struct {
+	UserID     string
+	Credit     int64
+	ProductID  string
+	Quantity   int64
+	QuoteToken string
+	PromoCode  string
+}
+
+type productSnapshot struct {
+	ProductID    string  `json:"product_id"`
+	Name         string  `json:"name"`
+	CreditAmount int64   `json:"credit_amount"`
+	BonusCredit  int64   `json:"bonus_credit"`
+	PricePeaka   *string `json:"price_peaka,omitempty"`
+	Quantity     int64   `json:"quantity"`
+}
+
+func (s OrderService) CreateOrder(ctx context.Context, p CreateOrderParams) (*models.Order, error) {
+	if p.UserID == "" {
 		return nil, ErrMissingUserID
 	}
-	if credit < s.MinCredit {
+
+	now := time.Now().UTC()
+	var product *models.Product
+	quantity := p.Quantity
+	if p.ProductID != "" {
+		if p.QuoteToken != "" {
+			return nil, ErrInvalidQuote
+		}
+		var err error
+		if quantity, err = productQuantity(quantity); err != nil {
+			return nil, err
+		}
+		product, err = s.Store.GetProduct(ctx, p.ProductID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, ErrProductInactive
+			}
+			return nil, err
+		}
+		if !productAvailable(product, now) {
+			return nil, ErrProductInactive
+		}
+	} else if p.Credit < s.MinCredit {
 		return nil, ErrInvalidCredit
 	}
 	if s.Deriver.XPub == "" {
 		return nil, ErrXpubNotConfigured
 	}
 
-	snap, err := s.Pricing.CurrentSnapshot(ctx)
-	if err != nil {
-		return nil, err
+	var (
+		snap        pricing.Snapshot
+		amountPeaka string
+		credit      = p.Credit
+		prodJSON    *string
+		err         error
+	)
+	switch {
+	case product != nil:
+		credit, err = productCredit(product, quantity)
+		if err != nil {
+			return nil, err
+		}
+		snap, amountPeaka, err = s.priceProduct(ctx, product, quantity)
+		if err != nil {
+			return nil, err
+		}
+		b, err := json.Marshal(productSnapshot{
+			ProductID:    product.ProductID,
+			Name:         product.Name,
+			CreditAmount: product.CreditAmount,
+			BonusCredit:  product.BonusCredit,
+			PricePeaka:   product.PricePeaka,
+			Quantity:     quantity,
+		})
+		if err != nil {
+			return nil, err
+		}
+		v := string(b)
+		prodJSON = &v
+	case p.QuoteToken != "":
+		claims, err := s.verifyQuote(p.QuoteToken, credit, now)
+		if err != nil {
+			return nil, err
+		}
+		snap, amountPeaka = claims.Snapshot, claims.AmountPeaka
+	default:
+		snap, amountPeaka, err = s.price(ctx, credit)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	amountPeaka, err := calcAmountPeaka(credit, snap.CreditPerDora, s.Decimals)
-	if err != nil {
-		return nil, err
+	var promoCode *string
+	if p.PromoCode != "" {
+		promo, err := s.lookupPromo(ctx, p.UserID, p.PromoCode, now)
+		if err != nil {
+			return nil, err
+		}
+		snap.Promo = promoSnapshot(promo)
+		if amountPeaka, credit, err = applyPromo(snap.Promo, amountPeaka, credit); err != nil {
+			return nil, err
+		}
+		promoCode = &promo.Code
 	}
 
 	idx, err := s.Store.NextDerivationIndex(ctx)
@@ -68,10 +165,9 @@ func (s OrderService) CreateOrder(ctx context.Context, userID string, credit int
 		return nil, err
 	}
 
-	now := time.Now().UTC()
 	order := &models.Order{
 		OrderID:          uuid.NewString(),
-		UserID:           userID,
+		UserID:           p.UserID,
 		RecipientAddress: addr,
 		DerivationIndex:  idx,
 		CreditRequested:  credit,
@@ -80,16 +176,134 @@ func (s OrderService) CreateOrder(ctx context.Context, userID string, credit int
 		PriceSnapshot:    string(snapJSON),
 		ExpiresAt:        now.Add(s.TTL),
 		Status:           models.OrderCreated,
+		ProductSnapshot:  prodJSON,
+		PromoCode:        promoCode,
 		CreatedAt:        now,
 		UpdatedAt:        now,
 	}
+	if product != nil {
+		order.ProductID = &product.ProductID
+		order.Quantity = &quantity
+	}
 
+	if s.Chain != nil {
+		if height, err := s.Chain.LatestHeight(ctx); err == nil {
+			order.CreatedHeight = &height
+		} else {
+			log.Printf("latest height for order %s failed: %v", order.OrderID, err)
+		}
+	}
 	if err := s.Store.CreateOrder(ctx, order); err != nil {
 		return nil, err
 	}
 	return order, nil
 }
 
+// RequoteOrder reprices an expired, unpaid order at the current rate and
+// reopens it on the same recipient address. Its product must still be
+// available and its promo code still usable by the user.
+func (s OrderService) RequoteOrder(ctx context.Context, userID, orderID string) (*models.Order, error) {
+	if userID == "" {
+		return nil, ErrMissingUserID
+	}
+	order, err := s.Store.GetOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.UserID != userID {
+		return nil, ErrOrderNotOwned
+	}
+	if !order.Status.CanTransitionTo(models.OrderCreated) {
+		return nil, ErrNotRequotable
+	}
+
+	now := time.Now().UTC()
+	if order.ProductID != nil {
+		product, err := s.Store.GetProduct(ctx, *order.ProductID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, ErrProductInactive
+			}
+			return nil, err
+		}
+		if !productAvailable(product, now) {
+			return nil, ErrProductInactive
+		}
+	}
+	if order.PromoCode != nil {
+		if _, err := s.lookupPromo(ctx, order.UserID, *order.PromoCode, now); err != nil {
+			return nil, err
+		}
+	}
+
+	snap, amountPeaka, err := s.reprice(ctx, order)
+	if err != nil {
+		return nil, err
+	}
+	snapJSON, err := json.Marshal(snap)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := now.Add(s.TTL)
+	n, err := s.Store.RequoteOrder(ctx, order.OrderID, amountPeaka, string(snapJSON), expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrNotRequotable
+	}
+	return s.Store.GetOrder(ctx, order.OrderID)
+}
+
+// reprice recomputes an order's amount the way CreateOrder did, keeping its
+// product and promo terms.
+func (s OrderService) reprice(ctx context.Context, order *models.Order) (pricing.Snapshot, string, error) {
+	var prev pricing.Snapshot
+	if err := json.Unmarshal([]byte(order.PriceSnapshot), &prev); err != nil {
+		return pricing.Snapshot{}, "", err
+	}
+
+	var (
+		snap        pricing.Snapshot
+		amountPeaka string
+		err         error
+	)
+	if order.ProductSnapshot != nil {
+		var ps productSnapshot
+		if err := json.Unmarshal([]byte(*order.ProductSnapshot), &ps); err != nil {
+			return pricing.Snapshot{}, "", err
+		}
+		product := &models.Product{
+			ProductID:    ps.ProductID,
+			CreditAmount: ps.CreditAmount,
+			BonusCredit:  ps.BonusCredit,
+			PricePeaka:   ps.PricePeaka,
+		}
+		snap, amountPeaka, err = s.priceProduct(ctx, product, ps.Quantity)
+	} else {
+		credit := order.CreditRequested
+		if prev.Promo != nil {
+			credit -= prev.Promo.BonusCredit
+		}
+		snap, amountPeaka, err = s.price(ctx, credit)
+	}
+	if err != nil {
+		return pricing.Snapshot{}, "", err
+	}
+
+	if prev.Promo != nil {
+		if prev.Promo.Kind == string(models.PromoPercent) {
+			amountPeaka, err = applyDiscount(amountPeaka, prev.Promo.DiscountBps)
+			if err != nil {
+				return pricing.Snapshot{}, "", err
+			}
+		}
+		snap.Promo = prev.Promo
+	}
+	return snap, amountPeaka, nil
+}
+
 func (s OrderService) GetOrder(ctx context.Context, orderID string) (*models.Order, error) {
 	return s.Store.GetOrder(ctx, orderID)
 }
@@ -102,8 +316,93 @@ func (s OrderService) ListOrdersByStatus(ctx context.Context, status string, lim
 	return s.Store.ListOrdersByStatus(ctx, status, limit, offset)
 }
 
+// ResolveReview settles a pending_review order from its recorded payment, or
+// rejects it without issuing credit.
+func (s OrderService) ResolveReview(ctx context.Context, orderID string, approve bool) (*models.Order, error) {
+	order, err := s.Store.GetOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != models.OrderPendingReview || order.TxHash == nil {
+		return nil, ErrNotInReview
+	}
+	payment, err := s.Store.GetPayment(ctx, *order.TxHash)
+	if err != nil {
+		return nil, err
+	}
+
+	status, creditIssued := models.OrderReviewRejected, (*int64)(nil)
+	if approve {
+		status, creditIssued = payments.SettlementStatus(order, payment.AmountPeaka, payment.BlockTime)
+	}
+	n, err := s.Store.ResolveReview(ctx, order.OrderID, status, payment.BlockTime, payment.TxHash, creditIssued)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrNotInReview
+	}
+	return s.Store.GetOrder(ctx, order.OrderID)
+}
+
+func (s OrderService) ListOrderQuotes(ctx context.Context, orderID string) ([]*models.OrderQuote, error) {
+	return s.Store.ListOrderQuotes(ctx, orderID)
+}
+
+func (s OrderService) RevenueByDay(ctx context.Context, from, to time.Time) ([]*models.RevenueRow, error) {
+	return s.Store.RevenueByDay(ctx, from, to)
+}
+
 func (s OrderService) ApplyPayment(ctx context.Context, order *models.Order, tx chain.Tx, amount string, sender string) (models.OrderStatus, bool, error) {
-	return payments.ApplyPayment(ctx, s.Store, order, tx, amount, sender)
+	val, err := s.Pricing.Valuate(ctx, order.PriceSnapshot, amount, s.Decimals)
+	if err != nil {
+		log.Printf("fiat valuation failed order=%s: %v", order.OrderID, err)
+	}
+	return payments.ApplyPayment(ctx, s.Store, order, tx, amount, sender, val, s.Verify)
+}
+
+func (s OrderService) price(ctx context.Context, credit int64) (pricing.Snapshot, string, error) {
+	snap, err := s.Pricing.CurrentSnapshot(ctx)
+	if err != nil {
+		return pricing.Snapshot{}, "", err
+	}
+	var amountPeaka string
+	if snap.Fiat != nil {
+		amountPeaka, err = calcAmountPeakaFiat(credit, snap.Fiat, s.Decimals)
+	} else {
+		var perDora int64
+		if perDora, err = snap.CreditPerDora.Int64(); err == nil {
+			amountPeaka, err = calcAmountPeaka(credit, perDora, s.Decimals)
+		}
+	}
+	if err != nil {
+		return pricing.Snapshot{}, "", err
+	}
+	if tier := s.Pricing.TierFor(credit); tier != nil {
+		amountPeaka, err = applyDiscount(amountPeaka, tier.DiscountBps)
+		if err != nil {
+			return pricing.Snapshot{}, "", err
+		}
+		snap.Tier = tier
+	}
+	return snap, amountPeaka, nil
+}
+
+// priceProduct charges the fixed product price when set, otherwise the
+// base credit amount at the current rate. Bonus credit is never charged.
+func (s OrderService) priceProduct(ctx context.Context, product *models.Product, quantity int64) (pricing.Snapshot, string, error) {
+	if product.PricePeaka == nil {
+		return s.price(ctx, product.CreditAmount*quantity)
+	}
+	snap, err := s.Pricing.CurrentSnapshot(ctx)
+	if err != nil {
+		return pricing.Snapshot{}, "", err
+	}
+	unit, ok := new(big.Int).SetString(*product.PricePeaka, 10)
+	if !ok {
+		return pricing.Snapshot{}, "", errors.New("invalid product price")
+	}
+	return snap, new(big.Int).Mul(unit, big.NewInt(quantity)).String(), nil
 }
 
 func calcAmountPeaka(creditRequested int64, creditPerDora int64, decimals int) (string, error) {
@@ -123,3 +422,30 @@ func calcAmountPeaka(creditRequested int64, creditPerDora int64, decimals int) (
 	}
 	return quot.String(), nil
 }
+
+// calcAmountPeakaFiat converts credit -> fiat -> DORA using the snapshot rate,
+// rounding the peaka amount up.
+func calcAmountPeakaFiat(creditRequested int64, rate *pricing.FiatRate, decimals int) (string, error) {
+	if decimals < 0 || decimals > 30 {
+		return "", errors.New("invalid decimals")
+	}
+	creditPrice, ok := new(big.Rat).SetString(rate.CreditPrice)
+	if !ok || creditPrice.Sign() <= 0 {
+		return "", errors.New("fiat credit price must be positive")
+	}
+	doraPrice, ok := new(big.Rat).SetString(rate.DoraPrice)
+	if !ok || doraPrice.Sign() <= 0 {
+		return "", errors.New("dora price must be positive")
+	}
+
+	pow := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	v := new(big.Rat).SetInt64(creditRequested)
+	v.Mul(v, creditPrice)
+	v.Mul(v, new(big.Rat).SetInt(pow))
+	v.Quo(v, doraPrice)
+	quot, rem := new(big.Int).QuoRem(v.Num(), v.Denom(), new(big.Int))
+	if rem.Sign() > 0 {
+		quot.Add(quot, big.NewInt(1))
+	}
+	return quot.String(), nil
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"DORAPollCredit/internal/models"
+	"DORAPollCredit/internal/pricing"
+)
+
+func TestReprice(t *testing.T) {
+	s := testOrderService()
+	productWith := func(price string) *string {
+		ps := productSnapshot{ProductID: "p1", Name: "Pack", CreditAmount: 100, BonusCredit: 20, Quantity: 3}
+		if price != "" {
+			ps.PricePeaka = &price
+		}
+		b, _ := json.Marshal(ps)
+		v := string(b)
+		return &v
+	}
+	snapshotWith := func(promo *pricing.Promo) string {
+		b, _ := json.Marshal(pricing.Snapshot{CreditPerDora: "50", Source: "fixed", Promo: promo})
+		return string(b)
+	}
+	bonus := &pricing.Promo{Code: "BONUS", Kind: string(models.PromoBonus), BonusCredit: 50}
+	percent := &pricing.Promo{Code: "TEN", Kind: string(models.PromoPercent), DiscountBps: 1000}
+
+	tests := []struct {
+		name   string
+		order  models.Order
+		amount string
+	}{
+		{name: "credit", order: models.Order{CreditRequested: 250, PriceSnapshot: snapshotWith(nil)}, amount: "2500000"},
+		{name: "bonus promo is not charged", order: models.Order{CreditRequested: 300, PriceSnapshot: snapshotWith(bonus)}, amount: "2500000"},
+		{name: "percent promo", order: models.Order{CreditRequested: 250, PriceSnapshot: snapshotWith(percent)}, amount: "2250000"},
+		{name: "product at the current rate", order: models.Order{CreditRequested: 360, PriceSnapshot: snapshotWith(nil), ProductSnapshot: productWith("")}, amount: "3000000"},
+		{name: "product at a fixed price", order: models.Order{CreditRequested: 360, PriceSnapshot: snapshotWith(percent), ProductSnapshot: productWith("1000000")}, amount: "2700000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snap, amount, err := s.reprice(context.Background(), &tt.order)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if amount != tt.amount {
+				t.Errorf("amount = %s, want %s", amount, tt.amount)
+			}
+			if snap.CreditPerDora != "100" {
+				t.Errorf("credit_per_dora = %s, want the current rate", snap.CreditPerDora)
+			}
+			var prev pricing.Snapshot
+			_ = json.Unmarshal([]byte(tt.order.PriceSnapshot), &prev)
+			if (snap.Promo == nil) != (prev.Promo == nil) || (snap.Promo != nil && *snap.Promo != *prev.Promo) {
+				t.Errorf("promo = %+v, want %+v kept", snap.Promo, prev.Promo)
+			}
+		})
+	}
+}
+
+func TestCalcAmountPeaka(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func() (string, error)
+		want string
+	}{
+		{name: "fixed", fn: func() (string, error) { return calcAmountPeaka(250, 100, 6) }, want: "2500000"},
+		{name: "fixed rounds up", fn: func() (string, error) { return calcAmountPeaka(1, 3, 0) }, want: "1"},
+		{name: "fiat", fn: func() (string, error) {
+			return calcAmountPeakaFiat(250, &pricing.FiatRate{CreditPrice: "0.01", DoraPrice: "0.5"}, 6)
+		}, want: "5000000"},
+		{name: "fiat rounds up", fn: func() (string, error) {
+			return calcAmountPeakaFiat(1, &pricing.FiatRate{CreditPrice: "1", DoraPrice: "3"}, 0)
+		}, want: "1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.fn()
+			if err != nil || got != tt.want {
+				t.Errorf("got %s, %v; want %s", got, err, tt.want)
+			}
+		})
+	}
+	if _, err := calcAmountPeaka(1, 0, 6); err == nil {
+		t.Error("zero credit per dora accepted")
+	}
+}
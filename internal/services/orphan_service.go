@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"DORAPollCredit/internal/models"
+)
+
+var (
+	ErrOrphanResolved    = errors.New("orphan payment already resolved")
+	ErrInvalidResolution = errors.New("invalid orphan payment resolution")
+)
+
+// OrphanTotals summarises the orphan payments matching a listing filter.
+type OrphanTotals struct {
+	Count       int64
+	AmountPeaka string
+}
+
+// OrphanResolution closes an orphan payment either by issuing Credit or by
+// recording the RefundTxHash that returned the funds.
+type OrphanResolution struct {
+	Action       string
+	Credit       int64
+	RefundTxHash string
+	Note         string
+}
+
+func (s OrderService) ListOrphanPayments(ctx context.Context, status string, limit, offset int) ([]*models.OrphanPayment, *OrphanTotals, error) {
+	items, err := s.Store.ListOrphanPayments(ctx, status, limit, offset)
+	if err != nil {
+		return nil, nil, err
+	}
+	count, amount, err := s.Store.OrphanTotals(ctx, status)
+	if err != nil {
+		return nil, nil, err
+	}
+	return items, &OrphanTotals{Count: count, AmountPeaka: amount}, nil
+}
+
+// ResolveOrphanPayment converts an open orphan payment to credits or marks it
+// refunded.
+func (s OrderService) ResolveOrphanPayment(ctx context.Context, txHash string, res OrphanResolution) (*models.OrphanPayment, error) {
+	var (
+		status       models.OrphanStatus
+		creditIssued *int64
+		refundTxHash *string
+	)
+	switch res.Action {
+	case "credit":
+		if res.Credit <= 0 {
+			return nil, ErrInvalidResolution
+		}
+		status, creditIssued = models.OrphanCredited, &res.Credit
+	case "refund":
+		hash := strings.ToUpper(strings.TrimSpace(res.RefundTxHash))
+		if hash == "" {
+			return nil, ErrInvalidResolution
+		}
+		status, refundTxHash = models.OrphanRefunded, &hash
+	default:
+		return nil, ErrInvalidResolution
+	}
+
+	orphan, err := s.Store.GetOrphanPayment(ctx, txHash)
+	if err != nil {
+		return nil, err
+	}
+	if orphan.Status != models.OrphanOpen {
+		return nil, ErrOrphanResolved
+	}
+	n, err := s.Store.ResolveOrphanPayment(ctx, orphan.TxHash, status, creditIssued, refundTxHash, strings.TrimSpace(res.Note))
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrOrphanResolved
+	}
+	return s.Store.GetOrphanPayment(ctx, orphan.TxHash)
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"DORAPollCredit/internal/models"
+	"DORAPollCredit/internal/store"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrInvalidProduct  = errors.New("invalid product")
+	ErrProductInactive = errors.New("product not available")
+	ErrInvalidQuantity = errors.New("invalid quantity")
+)
+
+const maxProductQuantity = 1000
+
+type ProductService struct {
+	Store *store.Store
+}
+
+func (s ProductService) ListProducts(ctx context.Context, activeOnly bool) ([]*models.Product, error) {
+	return s.Store.ListProducts(ctx, activeOnly)
+}
+
+func (s ProductService) GetProduct(ctx context.Context, productID string) (*models.Product, error) {
+	return s.Store.GetProduct(ctx, productID)
+}
+
+func (s ProductService) CreateProduct(ctx context.Context, p *models.Product) error {
+	if err := validateProduct(p); err != nil {
+		return err
+	}
+	return s.Store.CreateProduct(ctx, p)
+}
+
+func (s ProductService) UpdateProduct(ctx context.Context, p *models.Product) error {
+	if err := validateProduct(p); err != nil {
+		return err
+	}
+	n, err := s.Store.UpdateProduct(ctx, p)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (s ProductService) DeactivateProduct(ctx context.Context, productID string) error {
+	n, err := s.Store.DeactivateProduct(ctx, productID)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func validateProduct(p *models.Product) error {
+	p.ProductID = strings.TrimSpace(p.ProductID)
+	p.Name = strings.TrimSpace(p.Name)
+	if p.ProductID == "" || p.Name == "" {
+		return ErrInvalidProduct
+	}
+	if p.CreditAmount <= 0 || p.BonusCredit < 0 {
+		return ErrInvalidProduct
+	}
+	if p.PricePeaka != nil {
+		v, ok := new(big.Int).SetString(*p.PricePeaka, 10)
+		if !ok || v.Sign() <= 0 {
+			return ErrInvalidProduct
+		}
+	}
+	if p.ActiveFrom != nil && p.ActiveUntil != nil && !p.ActiveUntil.After(*p.ActiveFrom) {
+		return ErrInvalidProduct
+	}
+	if _, err := productCredit(p, maxProductQuantity); err != nil {
+		return ErrInvalidProduct
+	}
+	return nil
+}
+
+// productQuantity defaults a missing quantity to 1 and bounds it.
+func productQuantity(quantity int64) (int64, error) {
+	if quantity == 0 {
+		quantity = 1
+	}
+	if quantity < 1 || quantity > maxProductQuantity {
+		return 0, ErrInvalidQuantity
+	}
+	return quantity, nil
+}
+
+// productCredit is the credit issued for quantity units of p, or
+// ErrInvalidQuantity when it does not fit in an int64.
+func productCredit(p *models.Product, quantity int64) (int64, error) {
+	total := new(big.Int).Add(big.NewInt(p.CreditAmount), big.NewInt(p.BonusCredit))
+	total.Mul(total, big.NewInt(quantity))
+	if !total.IsInt64() {
+		return 0, ErrInvalidQuantity
+	}
+	return total.Int64(), nil
+}
+
+func productAvailable(p *models.Product, now time.Time) bool {
+	if !p.Active {
+		return false
+	}
+	if p.ActiveFrom != nil && now.Before(*p.ActiveFrom) {
+		return false
+	}
+	if p.ActiveUntil != nil && !now.Before(*p.ActiveUntil) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"DORAPollCredit/internal/models"
+)
+
+func TestProductAvailable(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	before, after := now.Add(-time.Hour), now.Add(time.Hour)
+
+	tests := []struct {
+		name string
+		p    models.Product
+		want bool
+	}{
+		{name: "active, no window", p: models.Product{Active: true}, want: true},
+		{name: "inactive", p: models.Product{Active: false}},
+		{name: "inside window", p: models.Product{Active: true, ActiveFrom: &before, ActiveUntil: &after}, want: true},
+		{name: "not started", p: models.Product{Active: true, ActiveFrom: &after}},
+		{name: "ended", p: models.Product{Active: true, ActiveUntil: &before}},
+		{name: "starts now", p: models.Product{Active: true, ActiveFrom: &now}, want: true},
+		{name: "ends now", p: models.Product{Active: true, ActiveUntil: &now}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := productAvailable(&tt.p, now); got != tt.want {
+				t.Errorf("productAvailable = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProductQuantity(t *testing.T) {
+	tests := []struct {
+		in   int64
+		want int64
+		err  error
+	}{
+		{in: 0, want: 1},
+		{in: 1, want: 1},
+		{in: maxProductQuantity, want: maxProductQuantity},
+		{in: maxProductQuantity + 1, err: ErrInvalidQuantity},
+		{in: -1, err: ErrInvalidQuantity},
+	}
+	for _, tt := range tests {
+		got, err := productQuantity(tt.in)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("productQuantity(%d) = %d, %v; want %d, %v", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestProductCredit(t *testing.T) {
+	tests := []struct {
+		name     string
+		p        models.Product
+		quantity int64
+		want     int64
+		err      error
+	}{
+		{name: "with bonus", p: models.Product{CreditAmount: 100, BonusCredit: 20}, quantity: 3, want: 360},
+		{name: "max int64", p: models.Product{CreditAmount: math.MaxInt64}, quantity: 1, want: math.MaxInt64},
+		{name: "sum overflows", p: models.Product{CreditAmount: math.MaxInt64, BonusCredit: 1}, quantity: 1, err: ErrInvalidQuantity},
+		{name: "product overflows", p: models.Product{CreditAmount: math.MaxInt64 / 2, BonusCredit: 1}, quantity: 2, err: ErrInvalidQuantity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := productCredit(&tt.p, tt.quantity)
+			if got != tt.want || !errors.Is(err, tt.err) {
+				t.Errorf("productCredit = %d, %v; want %d, %v", got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+func TestValidateProduct(t *testing.T) {
+	from := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	until := from.Add(24 * time.Hour)
+	price, zero := "1000000", "0"
+
+	tests := []struct {
+		name string
+		p    models.Product
+		ok   bool
+	}{
+		{name: "valid", p: models.Product{ProductID: " p1 ", Name: "Pack", CreditAmount: 100, BonusCredit: 10, PricePeaka: &price, ActiveFrom: &from, ActiveUntil: &until}, ok: true},
+		{name: "missing id", p: models.Product{Name: "Pack", CreditAmount: 100}},
+		{name: "no credit", p: models.Product{ProductID: "p1", Name: "Pack"}},
+		{name: "negative bonus", p: models.Product{ProductID: "p1", Name: "Pack", CreditAmount: 100, BonusCredit: -1}},
+		{name: "zero price", p: models.Product{ProductID: "p1", Name: "Pack", CreditAmount: 100, PricePeaka: &zero}},
+		{name: "empty window", p: models.Product{ProductID: "p1", Name: "Pack", CreditAmount: 100, ActiveFrom: &until, ActiveUntil: &from}},
+		{name: "credit overflows at max quantity", p: models.Product{ProductID: "p1", Name: "Pack", CreditAmount: math.MaxInt64 / 100}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateProduct(&tt.p)
+			if tt.ok != (err == nil) {
+				t.Fatalf("err = %v, want ok=%v", err, tt.ok)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidProduct) {
+				t.Errorf("err = %v, want ErrInvalidProduct", err)
+			}
+		})
+	}
+}
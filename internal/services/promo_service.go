@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+
+	"DORAPollCredit/internal/models"
+	"DORAPollCredit/internal/pricing"
+	"DORAPollCredit/internal/store"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrInvalidPromo   = errors.New("invalid promo code")
+	ErrPromoExhausted = store.ErrPromoExhausted
+)
+
+type PromoService struct {
+	Store *store.Store
+}
+
+func (s PromoService) ListPromoCodes(ctx context.Context) ([]*models.PromoCode, error) {
+	return s.Store.ListPromoCodes(ctx)
+}
+
+func (s PromoService) GetPromoCode(ctx context.Context, code string) (*models.PromoCode, error) {
+	return s.Store.GetPromoCode(ctx, normalizePromoCode(code))
+}
+
+func (s PromoService) CreatePromoCode(ctx context.Context, p *models.PromoCode) error {
+	if err := validatePromoCode(p); err != nil {
+		return err
+	}
+	return s.Store.CreatePromoCode(ctx, p)
+}
+
+func (s PromoService) UpdatePromoCode(ctx context.Context, p *models.PromoCode) error {
+	if err := validatePromoCode(p); err != nil {
+		return err
+	}
+	n, err := s.Store.UpdatePromoCode(ctx, p)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func validatePromoCode(p *models.PromoCode) error {
+	p.Code = normalizePromoCode(p.Code)
+	if p.Code == "" {
+		return ErrInvalidPromo
+	}
+	switch p.Kind {
+	case models.PromoPercent:
+		if p.DiscountBps <= 0 || p.DiscountBps >= 10000 {
+			return ErrInvalidPromo
+		}
+		p.BonusCredit = 0
+	case models.PromoBonus:
+		if p.BonusCredit <= 0 {
+			return ErrInvalidPromo
+		}
+		p.DiscountBps = 0
+	default:
+		return ErrInvalidPromo
+	}
+	if p.MaxRedemptions != nil && *p.MaxRedemptions <= 0 {
+		return ErrInvalidPromo
+	}
+	if p.PerUserLimit != nil && *p.PerUserLimit <= 0 {
+		return ErrInvalidPromo
+	}
+	return nil
+}
+
+func normalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// lookupPromo checks that a code can be used by userID right now. The caps
+// are checked again, under a row lock, when the order is paid and the
+// redemption is counted.
+func (s OrderService) lookupPromo(ctx context.Context, userID, code string, now time.Time) (*models.PromoCode, error) {
+	promo, err := s.Store.GetPromoCode(ctx, normalizePromoCode(code))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvalidPromo
+		}
+		return nil, err
+	}
+	if err := checkPromo(promo, 0, now); err != nil {
+		return nil, err
+	}
+	if promo.PerUserLimit != nil {
+		used, err := s.Store.CountUserRedemptions(ctx, promo.Code, userID)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkPromo(promo, used, now); err != nil {
+			return nil, err
+		}
+	}
+	return promo, nil
+}
+
+// checkPromo reports whether promo can be used at now by a user who has
+// redeemed it used times.
+func checkPromo(promo *models.PromoCode, used int64, now time.Time) error {
+	if !promo.Active || (promo.ExpiresAt != nil && !now.Before(*promo.ExpiresAt)) {
+		return ErrInvalidPromo
+	}
+	if promo.CapReached(used) {
+		return ErrPromoExhausted
+	}
+	return nil
+}
+
+func promoSnapshot(p *models.PromoCode) *pricing.Promo {
+	return &pricing.Promo{
+		Code:        p.Code,
+		Kind:        string(p.Kind),
+		DiscountBps: p.DiscountBps,
+		BonusCredit: p.BonusCredit,
+	}
+}
+
+// applyPromo returns an order's amount and credit after promo: a percent
+// code discounts the amount, a bonus code adds credit.
+func applyPromo(promo *pricing.Promo, amountPeaka string, credit int64) (string, int64, error) {
+	switch models.PromoKind(promo.Kind) {
+	case models.PromoPercent:
+		amount, err := applyDiscount(amountPeaka, promo.DiscountBps)
+		if err != nil {
+			return "", 0, ErrInvalidPromo
+		}
+		return amount, credit, nil
+	case models.PromoBonus:
+		if credit > math.MaxInt64-promo.BonusCredit {
+			return "", 0, ErrInvalidPromo
+		}
+		return amountPeaka, credit + promo.BonusCredit, nil
+	}
+	return amountPeaka, credit, nil
+}
+
+// applyDiscount reduces amount by bps basis points, rounding up. A discount
+// of 100% or one that leaves nothing to pay is rejected, since an order must
+// be settled by an on-chain transfer.
+func applyDiscount(amount string, bps int64) (string, error) {
+	if bps < 0 || bps >= 10000 {
+		return "", errors.New("invalid discount")
+	}
+	v, ok := new(big.Int).SetString(amount, 10)
+	if !ok {
+		return "", errors.New("invalid amount")
+	}
+	num := new(big.Int).Mul(v, big.NewInt(10000-bps))
+	quot, rem := new(big.Int).QuoRem(num, big.NewInt(10000), new(big.Int))
+	if rem.Sign() > 0 {
+		quot.Add(quot, big.NewInt(1))
+	}
+	if quot.Sign() <= 0 {
+		return "", errors.New("discounted amount is not positive")
+	}
+	return quot.String(), nil
+}
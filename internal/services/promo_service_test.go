@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"DORAPollCredit/internal/models"
+	"DORAPollCredit/internal/pricing"
+)
+
+func TestCheckPromo(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	two, one := int64(2), int64(1)
+
+	tests := []struct {
+		name string
+		p    models.PromoCode
+		used int64
+		want error
+	}{
+		{name: "usable", p: models.PromoCode{Active: true, ExpiresAt: &future}},
+		{name: "inactive", p: models.PromoCode{Active: false}, want: ErrInvalidPromo},
+		{name: "expired", p: models.PromoCode{Active: true, ExpiresAt: &past}, want: ErrInvalidPromo},
+		{name: "expires now", p: models.PromoCode{Active: true, ExpiresAt: &now}, want: ErrInvalidPromo},
+		{name: "below global cap", p: models.PromoCode{Active: true, MaxRedemptions: &two, RedeemedCount: 1}},
+		{name: "global cap reached", p: models.PromoCode{Active: true, MaxRedemptions: &two, RedeemedCount: 2}, want: ErrPromoExhausted},
+		{name: "below per-user cap", p: models.PromoCode{Active: true, PerUserLimit: &two}, used: 1},
+		{name: "per-user cap reached", p: models.PromoCode{Active: true, PerUserLimit: &one}, used: 1, want: ErrPromoExhausted},
+		{name: "per-user cap of another user", p: models.PromoCode{Active: true, PerUserLimit: &one, RedeemedCount: 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkPromo(&tt.p, tt.used, now); !errors.Is(err, tt.want) {
+				t.Errorf("checkPromo = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyPromo(t *testing.T) {
+	tests := []struct {
+		name       string
+		promo      pricing.Promo
+		amount     string
+		credit     int64
+		wantAmount string
+		wantCredit int64
+		err        error
+	}{
+		{name: "percent", promo: pricing.Promo{Kind: "percent", DiscountBps: 1000}, amount: "1000000", credit: 100, wantAmount: "900000", wantCredit: 100},
+		{name: "percent rounds up", promo: pricing.Promo{Kind: "percent", DiscountBps: 3333}, amount: "10", credit: 1, wantAmount: "7", wantCredit: 1},
+		{name: "percent leaves nothing", promo: pricing.Promo{Kind: "percent", DiscountBps: 9999}, amount: "0", credit: 1, err: ErrInvalidPromo},
+		{name: "bonus", promo: pricing.Promo{Kind: "bonus", BonusCredit: 50}, amount: "1000000", credit: 100, wantAmount: "1000000", wantCredit: 150},
+		{name: "bonus overflows", promo: pricing.Promo{Kind: "bonus", BonusCredit: 1}, amount: "1", credit: math.MaxInt64, err: ErrInvalidPromo},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amount, credit, err := applyPromo(&tt.promo, tt.amount, tt.credit)
+			if !errors.Is(err, tt.err) || amount != tt.wantAmount || credit != tt.wantCredit {
+				t.Errorf("applyPromo = %s, %d, %v; want %s, %d, %v", amount, credit, err, tt.wantAmount, tt.wantCredit, tt.err)
+			}
+		})
+	}
+}
+
+func TestValidatePromoCode(t *testing.T) {
+	zero := int64(0)
+	tests := []struct {
+		name string
+		p    models.PromoCode
+		ok   bool
+	}{
+		{name: "percent", p: models.PromoCode{Code: " spring ", Kind: models.PromoPercent, DiscountBps: 1500}, ok: true},
+		{name: "bonus", p: models.PromoCode{Code: "X", Kind: models.PromoBonus, BonusCredit: 10}, ok: true},
+		{name: "empty code", p: models.PromoCode{Code: " ", Kind: models.PromoPercent, DiscountBps: 1500}},
+		{name: "full discount", p: models.PromoCode{Code: "X", Kind: models.PromoPercent, DiscountBps: 10000}},
+		{name: "no bonus", p: models.PromoCode{Code: "X", Kind: models.PromoBonus}},
+		{name: "unknown kind", p: models.PromoCode{Code: "X", Kind: "fixed", BonusCredit: 10}},
+		{name: "zero global cap", p: models.PromoCode{Code: "X", Kind: models.PromoBonus, BonusCredit: 10, MaxRedemptions: &zero}},
+		{name: "zero per-user cap", p: models.PromoCode{Code: "X", Kind: models.PromoBonus, BonusCredit: 10, PerUserLimit: &zero}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePromoCode(&tt.p)
+			if tt.ok != (err == nil) {
+				t.Fatalf("err = %v, want ok=%v", err, tt.ok)
+			}
+			if err == nil && tt.p.Code != normalizePromoCode(tt.p.Code) {
+				t.Errorf("code %q not normalized", tt.p.Code)
+			}
+		})
+	}
+}
+
+func TestPriceTier(t *testing.T) {
+	s := testOrderService()
+	s.Pricing.Tiers = []pricing.Tier{{MinCredit: 1000, DiscountBps: 500}, {MinCredit: 5000, DiscountBps: 1000}}
+
+	tests := []struct {
+		credit   int64
+		amount   string
+		tierFrom int64
+	}{
+		{credit: 999, amount: "9990000"},
+		{credit: 1000, amount: "9500000", tierFrom: 1000},
+		{credit: 4999, amount: "47490500", tierFrom: 1000},
+		{credit: 5000, amount: "45000000", tierFrom: 5000},
+	}
+	for _, tt := range tests {
+		snap, amount, err := s.price(context.Background(), tt.credit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var tierFrom int64
+		if snap.Tier != nil {
+			tierFrom = snap.Tier.MinCredit
+		}
+		if amount != tt.amount || tierFrom != tt.tierFrom {
+			t.Errorf("price(%d) = %s tier %d, want %s tier %d", tt.credit, amount, tierFrom, tt.amount, tt.tierFrom)
+		}
+	}
+}
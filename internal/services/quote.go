@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"DORAPollCredit/internal/pricing"
+)
+
+var (
+	ErrInvalidQuote = errors.New("invalid quote token")
+	ErrQuoteExpired = errors.New("quote expired")
+)
+
+type Quote struct {
+	Credit      int64
+	AmountPeaka string
+	AmountDora  string
+	Denom       string
+	Snapshot    pricing.Snapshot
+	Token       string
+	ExpiresAt   time.Time
+}
+
+type quoteClaims struct {
+	Credit      int64            `json:"credit"`
+	AmountPeaka string           `json:"amount_peaka"`
+	Denom       string           `json:"denom"`
+	Snapshot    pricing.Snapshot `json:"snapshot"`
+	ExpiresAt   int64            `json:"exp"`
+}
+
+// Quote prices credit exactly like CreateOrder but does not persist anything.
+// A signed token is attached when QuoteSecret is configured.
+func (s OrderService) Quote(ctx context.Context, credit int64) (*Quote, error) {
+	if credit < s.MinCredit {
+		return nil, ErrInvalidCredit
+	}
+
+	snap, amountPeaka, err := s.price(ctx, credit)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &Quote{
+		Credit:      credit,
+		AmountPeaka: amountPeaka,
+		AmountDora:  formatUnits(amountPeaka, s.Decimals),
+		Denom:       s.Denom,
+		Snapshot:    snap,
+	}
+	if s.QuoteSecret == "" || s.QuoteTTL <= 0 {
+		return q, nil
+	}
+
+	q.ExpiresAt = time.Now().UTC().Add(s.QuoteTTL)
+	token, err := s.signQuote(quoteClaims{
+		Credit:      credit,
+		AmountPeaka: amountPeaka,
+		Denom:       s.Denom,
+		Snapshot:    snap,
+		ExpiresAt:   q.ExpiresAt.Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	q.Token = token
+	return q, nil
+}
+
+func (s OrderService) signQuote(claims quoteClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	body := base64.RawURLEncoding.EncodeToString(payload)
+	return body + "." + base64.RawURLEncoding.EncodeToString(s.quoteMAC(body)), nil
+}
+
+func (s OrderService) verifyQuote(token string, credit int64, now time.Time) (*quoteClaims, error) {
+	if s.QuoteSecret == "" {
+		return nil, ErrInvalidQuote
+	}
+	body, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidQuote
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, s.quoteMAC(body)) {
+		return nil, ErrInvalidQuote
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return nil, ErrInvalidQuote
+	}
+	var claims quoteClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidQuote
+	}
+	if claims.Credit != credit || claims.Denom != s.Denom {
+		return nil, ErrInvalidQuote
+	}
+	if now.Unix() > claims.ExpiresAt {
+		return nil, ErrQuoteExpired
+	}
+	return &claims, nil
+}
+
+func (s OrderService) quoteMAC(body string) []byte {
+	mac := hmac.New(sha256.New, []byte(s.QuoteSecret))
+	_, _ = mac.Write([]byte(body))
+	return mac.Sum(nil)
+}
+
+// formatUnits renders an integer base-unit amount as a decimal string.
+func formatUnits(amount string, decimals int) string {
+	v, ok := new(big.Int).SetString(amount, 10)
+	if !ok || decimals <= 0 {
+		return amount
+	}
+	pow := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	quot, rem := new(big.Int).QuoRem(v, pow, new(big.Int))
+	if rem.Sign() == 0 {
+		return quot.String()
+	}
+	frac := rem.String()
+	frac = strings.Repeat("0", decimals-len(frac)) + frac
+	return quot.String() + "." + strings.TrimRight(frac, "0")
+}
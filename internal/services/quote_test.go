@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"DORAPollCredit/internal/pricing"
+)
+
+func testOrderService() OrderService {
+	return OrderService{
+		Pricing:     pricing.Service{FixedCreditPerDora: 100},
+		MinCredit:   10,
+		Denom:       "peaka",
+		Decimals:    6,
+		QuoteSecret: "secret",
+		QuoteTTL:    time.Minute,
+	}
+}
+
+func TestQuote(t *testing.T) {
+	s := testOrderService()
+	q, err := s.Quote(context.Background(), 250)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.AmountPeaka != "2500000" || q.AmountDora != "2.5" || q.Token == "" {
+		t.Fatalf("quote = %+v", q)
+	}
+	if _, err := s.Quote(context.Background(), 9); !errors.Is(err, ErrInvalidCredit) {
+		t.Fatalf("below minimum: err = %v, want ErrInvalidCredit", err)
+	}
+
+	s.QuoteSecret = ""
+	if q, err := s.Quote(context.Background(), 250); err != nil || q.Token != "" {
+		t.Fatalf("without a secret: token = %q, %v; want none", q.Token, err)
+	}
+}
+
+func TestVerifyQuote(t *testing.T) {
+	s := testOrderService()
+	q, err := s.Quote(context.Background(), 250)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, sig, _ := strings.Cut(q.Token, ".")
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"credit":250,"amount_peaka":"1","denom":"peaka","exp":9999999999}`))
+	other := s
+	other.QuoteSecret = "other"
+	otherDenom := s
+	otherDenom.Denom = "uatom"
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		s      OrderService
+		token  string
+		credit int64
+		now    time.Time
+		want   error
+	}{
+		{name: "valid", s: s, token: q.Token, credit: 250, now: now},
+		{name: "other credit", s: s, token: q.Token, credit: 251, now: now, want: ErrInvalidQuote},
+		{name: "expired", s: s, token: q.Token, credit: 250, now: q.ExpiresAt.Add(time.Second), want: ErrQuoteExpired},
+		{name: "tampered body", s: s, token: forged + "." + sig, credit: 250, now: now, want: ErrInvalidQuote},
+		{name: "tampered signature", s: s, token: body + "." + flipFirst(sig), credit: 250, now: now, want: ErrInvalidQuote},
+		{name: "no signature", s: s, token: body, credit: 250, now: now, want: ErrInvalidQuote},
+		{name: "other secret", s: other, token: q.Token, credit: 250, now: now, want: ErrInvalidQuote},
+		{name: "other denom", s: otherDenom, token: q.Token, credit: 250, now: now, want: ErrInvalidQuote},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := tt.s.verifyQuote(tt.token, tt.credit, tt.now)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			if err == nil && (claims.AmountPeaka != q.AmountPeaka || claims.Snapshot.CreditPerDora != "100") {
+				t.Errorf("claims = %+v", claims)
+			}
+		})
+	}
+}
+
+func TestFormatUnits(t *testing.T) {
+	tests := []struct {
+		amount   string
+		decimals int
+		want     string
+	}{
+		{"2500000", 6, "2.5"},
+		{"1000000", 6, "1"},
+		{"1", 6, "0.000001"},
+		{"42", 0, "42"},
+	}
+	for _, tt := range tests {
+		if got := formatUnits(tt.amount, tt.decimals); got != tt.want {
+			t.Errorf("formatUnits(%s, %d) = %s, want %s", tt.amount, tt.decimals, got, tt.want)
+		}
+	}
+}
+
+// flipFirst changes the first character of s.
+func flipFirst(s string) string {
+	if s[0] == 'A' {
+		return "B" + s[1:]
+	}
+	return "A" + s[1:]
+}
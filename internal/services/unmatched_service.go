@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"DORAPollCredit/internal/chain"
+	"DORAPollCredit/internal/models"
+	"DORAPollCredit/internal/payments"
+	"DORAPollCredit/internal/store"
+)
+
+var (
+	ErrUnmatchedResolved = store.ErrUnmatchedResolved
+	ErrOrderNotPayable   = store.ErrOrderNotPayable
+	ErrTransferNotFound  = errors.New("tx does not contain the recorded transfer")
+)
+
+func (s OrderService) ListUnmatchedPayments(ctx context.Context, status string, limit, offset int) ([]*models.UnmatchedPayment, error) {
+	return s.Store.ListUnmatchedPayments(ctx, status, limit, offset)
+}
+
+func (s OrderService) GetUnmatchedPayment(ctx context.Context, id int64) (*models.UnmatchedPayment, error) {
+	return s.Store.GetUnmatchedPayment(ctx, id)
+}
+
+// ApplyUnmatchedPayment settles orderID with an unmatched payment. tx is the
+// node's current view of the payment tx and must still carry the recorded
+// transfer; the order must be created or expired. The transfer goes through
+// the same verification as the worker applies, so a failed check parks the
+// order in pending_review. The payment keeps the address it was actually sent
+// to, and is recorded, settled and resolved in one transaction.
+func (s OrderService) ApplyUnmatchedPayment(ctx context.Context, id int64, orderID string, tx *chain.Tx, note string) (*models.UnmatchedPayment, error) {
+	p, err := s.Store.GetUnmatchedPayment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if p.Status != models.UnmatchedOpen {
+		return nil, ErrUnmatchedResolved
+	}
+	if tx.Code != 0 || !hasUnmatchedTransfer(tx, p, s.Denom) {
+		return nil, ErrTransferNotFound
+	}
+	order, err := s.Store.GetOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != models.OrderCreated && order.Status != models.OrderExpired {
+		return nil, ErrOrderNotPayable
+	}
+
+	paidAt, reviewReason, err := s.Verify.Check(ctx, *tx, p.ToAddress, p.AmountPeaka)
+	if err != nil {
+		return nil, err
+	}
+	if paidAt.IsZero() {
+		paidAt = p.BlockTime
+	}
+	payment := &models.Payment{
+		TxHash:      p.TxHash,
+		OrderID:     order.OrderID,
+		FromAddress: p.FromAddress,
+		ToAddress:   p.ToAddress,
+		AmountPeaka: p.AmountPeaka,
+		Denom:       p.Denom,
+		Height:      p.Height,
+		BlockTime:   paidAt,
+	}
+	if reviewReason != "" {
+		payment.ReviewReason = &reviewReason
+	}
+	if val, err := s.Pricing.Valuate(ctx, order.PriceSnapshot, p.AmountPeaka, s.Decimals); err == nil && val != nil {
+		payment.FiatCurrency = &val.Currency
+		payment.FiatRate = &val.Rate
+		payment.FiatAmount = &val.Amount
+	}
+	status, creditIssued := payments.SettlementStatus(order, p.AmountPeaka, paidAt)
+	err = s.Store.ApplyUnmatchedPayment(ctx, id, payment, status, creditIssued, strings.TrimSpace(note))
+	if err != nil && !errors.Is(err, store.ErrPromoExhausted) {
+		return nil, err
+	}
+	return s.Store.GetUnmatchedPayment(ctx, id)
+}
+
+// DismissUnmatchedPayment closes an unmatched payment without applying it,
+// e.g. once it has been refunded off-band.
+func (s OrderService) DismissUnmatchedPayment(ctx context.Context, id int64, note string) (*models.UnmatchedPayment, error) {
+	n, err := s.Store.ResolveUnmatchedPayment(ctx, id, models.UnmatchedDismissed, nil, strings.TrimSpace(note))
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		if _, err := s.Store.GetUnmatchedPayment(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, ErrUnmatchedResolved
+	}
+	return s.Store.GetUnmatchedPayment(ctx, id)
+}
+
+func hasUnmatchedTransfer(tx *chain.Tx, p *models.UnmatchedPayment, denom string) bool {
+	for _, t := range payments.ExtractTransfers(tx.Events, denom) {
+		if t.Recipient == p.ToAddress && payments.CompareAmount(t.Amount, p.AmountPeaka) == 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,176 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"DORAPollCredit/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// settledStatuses are the order statuses after which further transfers to the
+// order address are orphan payments.
+var settledStatuses = []string{
+	string(models.OrderPaid),
+	string(models.OrderPaidLateReprice),
+	string(models.OrderLateNoCredit),
+	string(models.OrderUnderpaid),
+	string(models.OrderOverpaid),
+	string(models.OrderPendingReview),
+	string(models.OrderReviewRejected),
+}
+
+// ListSettledOrders returns settled orders paid at or after since.
+func (s *Store) ListSettledOrders(ctx context.Context, since time.Time) ([]*models.Order, error) {
+	rows, err := s.Pool.Query(ctx, `
+		SELECT `+orderColumns+`
+		FROM orders
+		WHERE status = ANY($1) AND paid_at >= $2
+	`, settledStatuses, since)
+	if err != nil {
+		return nil, err
+	}
+	return collectOrders(rows)
+}
+
+// InsertOrphanPayment records a transfer to a settled order's address. It is
+// a no-op when the tx is already recorded, either as an orphan or as the
+// order's own payment, and reports whether a row was added.
+func (s *Store) InsertOrphanPayment(ctx context.Context, p *models.OrphanPayment) (bool, error) {
+	res, err := s.Pool.Exec(ctx, `
+		INSERT INTO orphan_payments (
+			tx_hash, order_id, from_address, to_address,
+			amount_peaka, denom, height, block_time
+		)
+		SELECT $1,$2,$3,$4,$5,$6,$7,$8
+		WHERE NOT EXISTS (SELECT 1 FROM payments WHERE tx_hash=$1)
+		ON CONFLICT (tx_hash) DO NOTHING
+	`,
+		p.TxHash,
+		p.OrderID,
+		p.FromAddress,
+		p.ToAddress,
+		p.AmountPeaka,
+		p.Denom,
+		p.Height,
+		p.BlockTime,
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.RowsAffected() > 0, nil
+}
+
+func (s *Store) GetOrphanPayment(ctx context.Context, txHash string) (*models.OrphanPayment, error) {
+	row := s.Pool.QueryRow(ctx, `
+		SELECT `+orphanColumns+`
+		FROM orphan_payments WHERE tx_hash=$1
+	`, txHash)
+	return scanOrphan(row)
+}
+
+// ListOrphanPayments lists orphan payments newest first, optionally filtered
+// by status.
+func (s *Store) ListOrphanPayments(ctx context.Context, status string, limit, offset int) ([]*models.OrphanPayment, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 200 {
+		limit = 200
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	rows, err := s.Pool.Query(ctx, `
+		SELECT `+orphanColumns+`
+		FROM orphan_payments
+		WHERE $1 = '' OR status = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*models.OrphanPayment
+	for rows.Next() {
+		p, err := scanOrphan(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// OrphanTotals counts and sums the orphan payments with status, or all of
+// them when status is empty.
+func (s *Store) OrphanTotals(ctx context.Context, status string) (int64, string, error) {
+	var count int64
+	var amount string
+	err := s.Pool.QueryRow(ctx, `
+		SELECT count(*), COALESCE(SUM(amount_peaka::numeric), 0)::text
+		FROM orphan_payments
+		WHERE $1 = '' OR status = $1
+	`, status).Scan(&count, &amount)
+	return count, amount, err
+}
+
+// ResolveOrphanPayment closes an open orphan payment as credited or refunded.
+func (s *Store) ResolveOrphanPayment(ctx context.Context, txHash string, status models.OrphanStatus, creditIssued *int64, refundTxHash *string, note string) (int64, error) {
+	res, err := s.Pool.Exec(ctx, `
+		UPDATE orphan_payments
+		SET status=$2, credit_issued=$3, refund_tx_hash=$4, note=NULLIF($5, ''), resolved_at=now()
+		WHERE tx_hash=$1 AND status='open'
+	`, txHash, status, creditIssued, refundTxHash, note)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
+
+const orphanColumns = `tx_hash, order_id, COALESCE(from_address, ''), to_address,
+			amount_peaka, denom, height, block_time, status,
+			credit_issued, refund_tx_hash, note, resolved_at, created_at`
+
+func scanOrphan(row pgx.Row) (*models.OrphanPayment, error) {
+	var p models.OrphanPayment
+	var creditIssued sql.NullInt64
+	var refundTxHash, note sql.NullString
+	var resolvedAt sql.NullTime
+	err := row.Scan(
+		&p.TxHash,
+		&p.OrderID,
+		&p.FromAddress,
+		&p.ToAddress,
+		&p.AmountPeaka,
+		&p.Denom,
+		&p.Height,
+		&p.BlockTime,
+		&p.Status,
+		&creditIssued,
+		&refundTxHash,
+		&note,
+		&resolvedAt,
+		&p.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if creditIssued.Valid {
+		p.CreditIssued = &creditIssued.Int64
+	}
+	if refundTxHash.Valid {
+		p.RefundTxHash = &refundTxHash.String
+	}
+	if note.Valid {
+		p.Note = &note.String
+	}
+	if resolvedAt.Valid {
+		p.ResolvedAt = &resolvedAt.Time
+	}
+	return &p, nil
+}
@@ -0,0 +1,144 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"DORAPollCredit/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const productColumns = `product_id, name, credit_amount, bonus_credit, price_peaka,
+			active, active_from, active_until, created_at, updated_at`
+
+func (s *Store) CreateProduct(ctx context.Context, p *models.Product) error {
+	_, err := s.Pool.Exec(ctx, `
+		INSERT INTO products (
+			product_id, name, credit_amount, bonus_credit, price_peaka,
+			active, active_from, active_until
+		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+	`,
+		p.ProductID,
+		p.Name,
+		p.CreditAmount,
+		p.BonusCredit,
+		p.PricePeaka,
+		p.Active,
+		p.ActiveFrom,
+		p.ActiveUntil,
+	)
+	return err
+}
+
+func (s *Store) UpdateProduct(ctx context.Context, p *models.Product) (int64, error) {
+	res, err := s.Pool.Exec(ctx, `
+		UPDATE products
+		SET name=$2, credit_amount=$3, bonus_credit=$4, price_peaka=$5,
+			active=$6, active_from=$7, active_until=$8, updated_at=now()
+		WHERE product_id=$1
+	`,
+		p.ProductID,
+		p.Name,
+		p.CreditAmount,
+		p.BonusCredit,
+		p.PricePeaka,
+		p.Active,
+		p.ActiveFrom,
+		p.ActiveUntil,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
+
+// DeactivateProduct keeps the row so historical orders can still reference it.
+func (s *Store) DeactivateProduct(ctx context.Context, productID string) (int64, error) {
+	res, err := s.Pool.Exec(ctx, `
+		UPDATE products SET active=false, updated_at=now() WHERE product_id=$1
+	`, productID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
+
+func (s *Store) GetProduct(ctx context.Context, productID string) (*models.Product, error) {
+	row := s.Pool.QueryRow(ctx, `
+		SELECT `+productColumns+`
+		FROM products WHERE product_id=$1
+	`, productID)
+	return scanProduct(row)
+}
+
+func (s *Store) ListProducts(ctx context.Context, activeOnly bool) ([]*models.Product, error) {
+	var (
+		rows pgx.Rows
+		err  error
+	)
+	if activeOnly {
+		rows, err = s.Pool.Query(ctx, `
+			SELECT `+productColumns+`
+			FROM products
+			WHERE active
+				AND (active_from IS NULL OR active_from <= now())
+				AND (active_until IS NULL OR active_until > now())
+			ORDER BY credit_amount ASC
+		`)
+	} else {
+		rows, err = s.Pool.Query(ctx, `
+			SELECT `+productColumns+`
+			FROM products
+			ORDER BY created_at DESC
+		`)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []*models.Product
+	for rows.Next() {
+		p, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	return products, rows.Err()
+}
+
+func scanProduct(row pgx.Row) (*models.Product, error) {
+	var p models.Product
+	var pricePeaka sql.NullString
+	var activeFrom sql.NullTime
+	var activeUntil sql.NullTime
+
+	err := row.Scan(
+		&p.ProductID,
+		&p.Name,
+		&p.CreditAmount,
+		&p.BonusCredit,
+		&pricePeaka,
+		&p.Active,
+		&activeFrom,
+		&activeUntil,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if pricePeaka.Valid {
+		p.PricePeaka = &pricePeaka.String
+	}
+	if activeFrom.Valid {
+		p.ActiveFrom = &activeFrom.Time
+	}
+	if activeUntil.Valid {
+		p.ActiveUntil = &activeUntil.Time
+	}
+	return &p, nil
+}
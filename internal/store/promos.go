@@ -0,0 +1,193 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"DORAPollCredit/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrPromoExhausted means redeeming the order's promo code would exceed its
+// max_redemptions or per_user_limit.
+var ErrPromoExhausted = errors.New("promo code usage limit reached")
+
+const promoColumns = `code, kind, discount_bps, bonus_credit, max_redemptions,
+			per_user_limit, redeemed_count, active, expires_at, created_at, updated_at`
+
+func (s *Store) CreatePromoCode(ctx context.Context, p *models.PromoCode) error {
+	_, err := s.Pool.Exec(ctx, `
+		INSERT INTO promo_codes (
+			code, kind, discount_bps, bonus_credit, max_redemptions,
+			per_user_limit, active, expires_at
+		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+	`,
+		p.Code,
+		p.Kind,
+		p.DiscountBps,
+		p.BonusCredit,
+		p.MaxRedemptions,
+		p.PerUserLimit,
+		p.Active,
+		p.ExpiresAt,
+	)
+	return err
+}
+
+func (s *Store) UpdatePromoCode(ctx context.Context, p *models.PromoCode) (int64, error) {
+	res, err := s.Pool.Exec(ctx, `
+		UPDATE promo_codes
+		SET kind=$2, discount_bps=$3, bonus_credit=$4, max_redemptions=$5,
+			per_user_limit=$6, active=$7, expires_at=$8, updated_at=now()
+		WHERE code=$1
+	`,
+		p.Code,
+		p.Kind,
+		p.DiscountBps,
+		p.BonusCredit,
+		p.MaxRedemptions,
+		p.PerUserLimit,
+		p.Active,
+		p.ExpiresAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
+
+func (s *Store) GetPromoCode(ctx context.Context, code string) (*models.PromoCode, error) {
+	row := s.Pool.QueryRow(ctx, `
+		SELECT `+promoColumns+`
+		FROM promo_codes WHERE code=$1
+	`, code)
+	return scanPromoCode(row)
+}
+
+func (s *Store) ListPromoCodes(ctx context.Context) ([]*models.PromoCode, error) {
+	rows, err := s.Pool.Query(ctx, `
+		SELECT `+promoColumns+`
+		FROM promo_codes
+		ORDER BY created_at DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var promos []*models.PromoCode
+	for rows.Next() {
+		p, err := scanPromoCode(rows)
+		if err != nil {
+			return nil, err
+		}
+		promos = append(promos, p)
+	}
+	return promos, rows.Err()
+}
+
+func (s *Store) CountUserRedemptions(ctx context.Context, code, userID string) (int64, error) {
+	var n int64
+	err := s.Pool.QueryRow(ctx, `
+		SELECT count(*) FROM promo_redemptions WHERE code=$1 AND user_id=$2
+	`, code, userID).Scan(&n)
+	return n, err
+}
+
+// redeemPromo records the order's promo redemption at most once and bumps the
+// code's counter. The code's row is locked first, so concurrent settlements
+// count each other's redemptions. When enforce is set and either cap is
+// already reached it records nothing and returns ErrPromoExhausted.
+func redeemPromo(ctx context.Context, tx pgx.Tx, orderID string, enforce bool) error {
+	var code, userID string
+	var maxRedemptions, perUserLimit sql.NullInt64
+	var redeemed int64
+	err := tx.QueryRow(ctx, `
+		SELECT p.code, o.user_id, p.max_redemptions, p.per_user_limit, p.redeemed_count
+		FROM orders o JOIN promo_codes p ON p.code = o.promo_code
+		WHERE o.order_id=$1
+		FOR UPDATE OF p
+	`, orderID).Scan(&code, &userID, &maxRedemptions, &perUserLimit, &redeemed)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var done bool
+	if err := tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM promo_redemptions WHERE order_id=$1)
+	`, orderID).Scan(&done); err != nil {
+		return err
+	}
+	if done {
+		return nil
+	}
+	if enforce {
+		promo := models.PromoCode{RedeemedCount: redeemed}
+		if maxRedemptions.Valid {
+			promo.MaxRedemptions = &maxRedemptions.Int64
+		}
+		var used int64
+		if perUserLimit.Valid {
+			promo.PerUserLimit = &perUserLimit.Int64
+			if err := tx.QueryRow(ctx, `
+				SELECT count(*) FROM promo_redemptions WHERE code=$1 AND user_id=$2
+			`, code, userID).Scan(&used); err != nil {
+				return err
+			}
+		}
+		if promo.CapReached(used) {
+			return ErrPromoExhausted
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO promo_redemptions (order_id, code, user_id) VALUES ($1,$2,$3)
+	`, orderID, code, userID); err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE promo_codes SET redeemed_count=redeemed_count+1, updated_at=now()
+		WHERE code=$1
+	`, code)
+	return err
+}
+
+func scanPromoCode(row pgx.Row) (*models.PromoCode, error) {
+	var p models.PromoCode
+	var maxRedemptions sql.NullInt64
+	var perUserLimit sql.NullInt64
+	var expiresAt sql.NullTime
+
+	err := row.Scan(
+		&p.Code,
+		&p.Kind,
+		&p.DiscountBps,
+		&p.BonusCredit,
+		&maxRedemptions,
+		&perUserLimit,
+		&p.RedeemedCount,
+		&p.Active,
+		&expiresAt,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if maxRedemptions.Valid {
+		p.MaxRedemptions = &maxRedemptions.Int64
+	}
+	if perUserLimit.Valid {
+		p.PerUserLimit = &perUserLimit.Int64
+	}
+	if expiresAt.Valid {
+		p.ExpiresAt = &expiresAt.Time
+	}
+	return &p, nil
+}
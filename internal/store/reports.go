@@ -0,0 +1,38 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"DORAPollCredit/internal/models"
+)
+
+// RevenueByDay sums payments received in [from, to) per UTC day and fiat
+// currency. Payments recorded without a fiat valuation report an empty currency.
+func (s *Store) RevenueByDay(ctx context.Context, from, to time.Time) ([]*models.RevenueRow, error) {
+	rows, err := s.Pool.Query(ctx, `
+		SELECT date_trunc('day', block_time AT TIME ZONE 'UTC') AS day,
+			COALESCE(fiat_currency, '') AS fiat_currency,
+			count(*),
+			SUM(amount_peaka::numeric)::text,
+			COALESCE(SUM(fiat_amount), 0)::text
+		FROM payments
+		WHERE block_time >= $1 AND block_time < $2
+		GROUP BY 1, 2
+		ORDER BY 1, 2
+	`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*models.RevenueRow
+	for rows.Next() {
+		var row models.RevenueRow
+		if err := rows.Scan(&row.Day, &row.FiatCurrency, &row.Payments, &row.AmountPeaka, &row.FiatAmount); err != nil {
+			return nil, err
+		}
+		out = append(out, &row)
+	}
+	return out, rows.Err()
+}
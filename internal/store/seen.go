@@ -0,0 +1,58 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"DORAPollCredit/internal/models"
+)
+
+// InsertSeenPayment records a tx to toAddress seen on the WebSocket stream,
+// for orderID or, when it is empty, as an unmatched candidate. It is handled
+// once confirmed, by the same logic as the polling path.
+func (s *Store) InsertSeenPayment(ctx context.Context, txHash, orderID, toAddress string, height int64) error {
+	_, err := s.Pool.Exec(ctx, `
+		INSERT INTO seen_payments (tx_hash, order_id, to_address, height)
+		VALUES ($1, NULLIF($2, ''), $3, $4)
+		ON CONFLICT (tx_hash, to_address) DO NOTHING
+	`, txHash, orderID, toAddress, height)
+	return err
+}
+
+// ListSeenPayments returns seen payments at or below height, oldest first.
+func (s *Store) ListSeenPayments(ctx context.Context, height int64) ([]*models.SeenPayment, error) {
+	rows, err := s.Pool.Query(ctx, `
+		SELECT tx_hash, COALESCE(order_id, ''), to_address, height, seen_at
+		FROM seen_payments
+		WHERE height <= $1
+		ORDER BY height, seen_at
+	`, height)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*models.SeenPayment
+	for rows.Next() {
+		var p models.SeenPayment
+		if err := rows.Scan(&p.TxHash, &p.OrderID, &p.ToAddress, &p.Height, &p.SeenAt); err != nil {
+			return nil, err
+		}
+		out = append(out, &p)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) DeleteSeenPayment(ctx context.Context, txHash, toAddress string) error {
+	_, err := s.Pool.Exec(ctx, `DELETE FROM seen_payments WHERE tx_hash=$1 AND to_address=$2`, txHash, toAddress)
+	return err
+}
+
+// PruneSeenPayments drops entries seen before cutoff that never finalized.
+func (s *Store) PruneSeenPayments(ctx context.Context, cutoff time.Time) (int64, error) {
+	res, err := s.Pool.Exec(ctx, `DELETE FROM seen_payments WHERE seen_at < $1`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
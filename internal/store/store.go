@@ -10,6 +10,7 @@ import (
 	"DORAPollCredit/internal/models"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -32,8 +33,9 @@ func (s *Store) CreateOrder(ctx context.Context, order *models.Order) error {
 		INSERT INTO orders (
 			order_id, user_id, recipient_address, derivation_index,
 			credit_requested, amount_peaka, denom, price_snapshot,
-			expires_at, status, paid_at, tx_hash, credit_issued
-		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)
+			expires_at, status, paid_at, tx_hash, credit_issued,
+			product_id, quantity, product_snapshot, promo_code, created_height
+		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18)
 	`,
 		order.OrderID,
 		order.UserID,
@@ -48,59 +50,63 @@ func (s *Store) CreateOrder(ctx context.Context, order *models.Order) error {
 		order.PaidAt,
 		order.TxHash,
 		order.CreditIssued,
+		order.ProductID,
+		order.Quantity,
+		order.ProductSnapshot,
+		order.PromoCode,
+		order.CreatedHeight,
 	)
 	return err
 }
 
 func (s *Store) GetOrder(ctx context.Context, orderID string) (*models.Order, error) {
 	row := s.Pool.QueryRow(ctx, `
-		SELECT order_id, user_id, recipient_address, derivation_index,
-			credit_requested, amount_peaka, denom, price_snapshot,
-			expires_at, status, paid_at, tx_hash, credit_issued,
-			created_at, updated_at
+		SELECT `+orderColumns+`
 		FROM orders WHERE order_id=$1
 	`, orderID)
+	return scanOrder(row)
+}
 
-	var order models.Order
-	var paidAt sql.NullTime
-	var txHash sql.NullString
-	var creditIssued sql.NullInt64
+func (s *Store) GetSyncHeight(ctx context.Context) (int64, error) {
+	return s.getSyncInt(ctx, "last_processed_height")
+}
 
-	err := row.Scan(
-		&order.OrderID,
-		&order.UserID,
-		&order.RecipientAddress,
-		&order.DerivationIndex,
-		&order.CreditRequested,
-		&order.AmountPeaka,
-		&order.Denom,
-		&order.PriceSnapshot,
-		&order.ExpiresAt,
-		&order.Status,
-		&paidAt,
-		&txHash,
-		&creditIssued,
-		&order.CreatedAt,
-		&order.UpdatedAt,
-	)
-	if err != nil {
-		return nil, err
-	}
+func (s *Store) SetSyncHeight(ctx context.Context, height int64) error {
+	return s.setSyncInt(ctx, "last_processed_height", height)
+}
 
-	if paidAt.Valid {
-		order.PaidAt = &paidAt.Time
-	}
-	if txHash.Valid {
-		order.TxHash = &txHash.String
-	}
-	if creditIssued.Valid {
-		order.CreditIssued = &creditIssued.Int64
-	}
-	return &order, nil
+// GetWSHeight returns the highest block height observed on the WebSocket
+// stream, or 0 if none was recorded.
+func (s *Store) GetWSHeight(ctx context.Context) (int64, error) {
+	return s.getSyncInt(ctx, "ws_last_height")
 }
 
-func (s *Store) GetSyncHeight(ctx context.Context) (int64, error) {
-	row := s.Pool.QueryRow(ctx, "SELECT value FROM sync_state WHERE key='last_processed_height'")
+func (s *Store) SetWSHeight(ctx context.Context, height int64) error {
+	return s.setSyncInt(ctx, "ws_last_height", height)
+}
+
+// SetOrderScannedHeight advances the order's scan cursor; it never moves back.
+func (s *Store) SetOrderScannedHeight(ctx context.Context, orderID string, height int64) error {
+	_, err := s.Pool.Exec(ctx, `
+		UPDATE orders
+		SET scanned_height = GREATEST(COALESCE(scanned_height, 0), $2)
+		WHERE order_id=$1
+	`, orderID, height)
+	return err
+}
+
+// GetLateScanHeight returns the last height scanned for transfers to the
+// lookahead addresses that have no order yet.
+func (s *Store) GetLateScanHeight(ctx context.Context) (int64, error) {
+	return s.getSyncInt(ctx, "late_scan_height")
+}
+
+func (s *Store) SetLateScanHeight(ctx context.Context, height int64) error {
+	return s.setSyncInt(ctx, "late_scan_height", height)
+}
+
+func (s *Store) getSyncInt(ctx context.Context, key string) (int64, error) {
+	row := s.Pool.QueryRow(ctx, "SELECT value FROM sync_state WHERE key=$1", key)
 	var v string
 	if err := row.Scan(&v); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -111,66 +117,39 @@ func (s *Store) GetSyncHeight(ctx context.Context) (int64, error) {
 	return strconv.ParseInt(v, 10, 64)
 }
 
-func (s *Store) SetSyncHeight(ctx context.Context, height int64) error {
+func (s *Store) setSyncInt(ctx context.Context, key string, value int64) error {
 	_, err := s.Pool.Exec(ctx, `
 		INSERT INTO sync_state (key, value)
-		VALUES ('last_processed_height', $1)
+		VALUES ($1, $2)
 		ON CONFLICT (key) DO UPDATE SET value=EXCLUDED.value
-	`, strconv.FormatInt(height, 10))
+	`, key, strconv.FormatInt(value, 10))
 	return err
 }
 
 func (s *Store) ListPendingOrders(ctx context.Context) ([]*models.Order, error) {
 	rows, err := s.Pool.Query(ctx, `
-		SELECT order_id, user_id, recipient_address, derivation_index,
-			credit_requested, amount_peaka, denom, price_snapshot,
-			expires_at, status, paid_at, tx_hash, credit_issued,
-			created_at, updated_at
+		SELECT `+orderColumns+`
 		FROM orders
 		WHERE status IN ('created')
 	`)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	return collectOrders(rows)
+}
 
-	var orders []*models.Order
-	for rows.Next() {
-		var order models.Order
-		var paidAt sql.NullTime
-		var txHash sql.NullString
-		var creditIssued sql.NullInt64
-		if err := rows.Scan(
-			&order.OrderID,
-			&order.UserID,
-			&order.RecipientAddress,
-			&order.DerivationIndex,
-			&order.CreditRequested,
-			&order.AmountPeaka,
-			&order.Denom,
-			&order.PriceSnapshot,
-			&order.ExpiresAt,
-			&order.Status,
-			&paidAt,
-			&txHash,
-			&creditIssued,
-			&order.CreatedAt,
-			&order.UpdatedAt,
-		); err != nil {
-			return nil, err
-		}
-		if paidAt.Valid {
-			order.PaidAt = &paidAt.Time
-		}
-		if txHash.Valid {
-			order.TxHash = &txHash.String
-		}
-		if creditIssued.Valid {
-			order.CreditIssued = &creditIssued.Int64
-		}
-		orders = append(orders, &order)
+// ListExpiredOrders returns expired orders whose expires_at is at or after
+// since, i.e. still inside the late-payment retention window.
+func (s *Store) ListExpiredOrders(ctx context.Context, since time.Time) ([]*models.Order, error) {
+	rows, err := s.Pool.Query(ctx, `
+		SELECT `+orderColumns+`
+		FROM orders
+		WHERE status='expired' AND expires_at >= $1
+	`, since)
+	if err != nil {
+		return nil, err
 	}
-	return orders, rows.Err()
+	return collectOrders(rows)
 }
 
 func (s *Store) ListOrdersByStatus(ctx context.Context, status string, limit, offset int) ([]*models.Order, error) {
@@ -190,20 +169,14 @@ func (s *Store) ListOrdersByStatus(ctx context.Context, status string, limit, of
 	)
 	if status == "" {
 		rows, err = s.Pool.Query(ctx, `
-			SELECT order_id, user_id, recipient_address, derivation_index,
-				credit_requested, amount_peaka, denom, price_snapshot,
-				expires_at, status, paid_at, tx_hash, credit_issued,
-				created_at, updated_at
+			SELECT `+orderColumns+`
 			FROM orders
 			ORDER BY created_at DESC
 			LIMIT $1 OFFSET $2
 		`, limit, offset)
 	} else {
 		rows, err = s.Pool.Query(ctx, `
-			SELECT order_id, user_id, recipient_address, derivation_index,
-				credit_requested, amount_peaka, denom, price_snapshot,
-				expires_at, status, paid_at, tx_hash, credit_issued,
-				created_at, updated_at
+			SELECT `+orderColumns+`
 			FROM orders
 			WHERE status=$1
 			ORDER BY created_at DESC
@@ -213,47 +186,11 @@ func (s *Store) ListOrdersByStatus(ctx context.Context, status string, limit, of
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var orders []*models.Order
-	for rows.Next() {
-		var order models.Order
-		var paidAt sql.NullTime
-		var txHash sql.NullString
-		var creditIssued sql.NullInt64
-		if err := rows.Scan(
-			&order.OrderID,
-			&order.UserID,
-			&order.RecipientAddress,
-			&order.DerivationIndex,
-			&order.CreditRequested,
-			&order.AmountPeaka,
-			&order.Denom,
-			&order.PriceSnapshot,
-			&order.ExpiresAt,
-			&order.Status,
-			&paidAt,
-			&txHash,
-			&creditIssued,
-			&order.CreatedAt,
-			&order.UpdatedAt,
-		); err != nil {
-			return nil, err
-		}
-		if paidAt.Valid {
-			order.PaidAt = &paidAt.Time
-		}
-		if txHash.Valid {
-			order.TxHash = &txHash.String
-		}
-		if creditIssued.Valid {
-			order.CreditIssued = &creditIssued.Int64
-		}
-		orders = append(orders, &order)
-	}
-	return orders, rows.Err()
+	return collectOrders(rows)
 }
 
+// MarkExpired moves overdue created orders to expired. A requoted order is back
+// in created with a fresh expires_at and expires again through this path.
 func (s *Store) MarkExpired(ctx context.Context, now time.Time) error {
 	_, err := s.Pool.Exec(ctx, `
 		UPDATE orders
@@ -263,12 +200,84 @@ func (s *Store) MarkExpired(ctx context.Context, now time.Time) error {
 	return err
 }
 
+// RequoteOrder archives the order's current quote and reopens it with a new
+// amount, snapshot and expiry. Only expired orders without payments qualify.
+func (s *Store) RequoteOrder(ctx context.Context, orderID, amountPeaka, priceSnapshot string, expiresAt time.Time) (int64, error) {
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO order_quotes (order_id, amount_peaka, price_snapshot, expires_at)
+		SELECT order_id, amount_peaka, price_snapshot, expires_at
+		FROM orders
+		WHERE order_id=$1 AND status='expired'
+			AND NOT EXISTS (SELECT 1 FROM payments WHERE payments.order_id=orders.order_id)
+	`, orderID)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := tx.Exec(ctx, `
+		UPDATE orders
+		SET status='created', amount_peaka=$2, price_snapshot=$3, expires_at=$4, updated_at=now()
+		WHERE order_id=$1 AND status='expired'
+			AND NOT EXISTS (SELECT 1 FROM payments WHERE payments.order_id=orders.order_id)
+	`, orderID, amountPeaka, priceSnapshot, expiresAt)
+	if err != nil {
+		return 0, err
+	}
+	if res.RowsAffected() == 0 {
+		return 0, nil
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
+
+func (s *Store) ListOrderQuotes(ctx context.Context, orderID string) ([]*models.OrderQuote, error) {
+	rows, err := s.Pool.Query(ctx, `
+		SELECT order_id, amount_peaka, price_snapshot, expires_at, replaced_at
+		FROM order_quotes
+		WHERE order_id=$1
+		ORDER BY id ASC
+	`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var quotes []*models.OrderQuote
+	for rows.Next() {
+		var q models.OrderQuote
+		if err := rows.Scan(&q.OrderID, &q.AmountPeaka, &q.PriceSnapshot, &q.ExpiresAt, &q.ReplacedAt); err != nil {
+			return nil, err
+		}
+		quotes = append(quotes, &q)
+	}
+	return quotes, rows.Err()
+}
+
+// execer is satisfied by both the pool and a pgx.Tx, so a statement can run
+// on its own or as part of a larger transaction.
+type execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
 func (s *Store) InsertPayment(ctx context.Context, payment *models.Payment) error {
-	_, err := s.Pool.Exec(ctx, `
+	return insertPayment(ctx, s.Pool, payment)
+}
+
+func insertPayment(ctx context.Context, db execer, payment *models.Payment) error {
+	_, err := db.Exec(ctx, `
 		INSERT INTO payments (
 			tx_hash, order_id, from_address, to_address,
-			amount_peaka, denom, height, block_time
-		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+			amount_peaka, denom, height, block_time,
+			fiat_currency, fiat_rate, fiat_amount, review_reason
+		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
 		ON CONFLICT (tx_hash) DO NOTHING
 	`,
 		payment.TxHash,
@@ -279,87 +288,195 @@ func (s *Store) InsertPayment(ctx context.Context, payment *models.Payment) erro
 		payment.Denom,
 		payment.Height,
 		payment.BlockTime,
+		payment.FiatCurrency,
+		payment.FiatRate,
+		payment.FiatAmount,
+		payment.ReviewReason,
 	)
 	return err
 }
 
+// UpdateOrderPayment settles the order and, when credit is issued, counts its
+// promo code redemption in the same transaction. If that would exceed the
+// code's caps, the order is parked in pending_review instead, the payment's
+// review_reason says why, and ErrPromoExhausted is returned.
 func (s *Store) UpdateOrderPayment(ctx context.Context, orderID string, status models.OrderStatus, paidAt time.Time, txHash string, creditIssued *int64) (int64, error) {
-	res, err := s.Pool.Exec(ctx, `
+	return s.settleOrder(ctx, []models.OrderStatus{models.OrderCreated, models.OrderExpired}, orderID, status, paidAt, txHash, creditIssued, true)
+}
+
+// ResolveReview settles an order parked in pending_review. An approval by an
+// operator redeems the promo code even past its caps.
+func (s *Store) ResolveReview(ctx context.Context, orderID string, status models.OrderStatus, paidAt time.Time, txHash string, creditIssued *int64) (int64, error) {
+	return s.settleOrder(ctx, []models.OrderStatus{models.OrderPendingReview}, orderID, status, paidAt, txHash, creditIssued, false)
+}
+
+// MarkPendingReview parks an unsettled order whose payment failed verification.
+func (s *Store) MarkPendingReview(ctx context.Context, orderID string, paidAt time.Time, txHash string) (int64, error) {
+	return markPendingReview(ctx, s.Pool, orderID, paidAt, txHash)
+}
+
+func markPendingReview(ctx context.Context, db execer, orderID string, paidAt time.Time, txHash string) (int64, error) {
+	res, err := db.Exec(ctx, `
 		UPDATE orders
-		SET status=$2, paid_at=$3, tx_hash=$4, credit_issued=$5, updated_at=now()
+		SET status='pending_review', paid_at=$2, tx_hash=$3, updated_at=now()
 		WHERE order_id=$1 AND status IN ('created','expired')
-	`, orderID, status, paidAt, txHash, creditIssued)
+	`, orderID, paidAt, txHash)
 	if err != nil {
 		return 0, err
 	}
 	return res.RowsAffected(), nil
 }
 
-func (s *Store) GetPendingOrderByRecipient(ctx context.Context, recipient string) (*models.Order, error) {
-	row := s.Pool.QueryRow(ctx, `
-		SELECT order_id, user_id, recipient_address, derivation_index,
-			credit_requested, amount_peaka, denom, price_snapshot,
-			expires_at, status, paid_at, tx_hash, credit_issued,
-			created_at, updated_at
-		FROM orders
-		WHERE recipient_address=$1 AND status IN ('created')
-		LIMIT 1
-	`, recipient)
+func (s *Store) settleOrder(ctx context.Context, from []models.OrderStatus, orderID string, status models.OrderStatus, paidAt time.Time, txHash string, creditIssued *int64, enforceCaps bool) (int64, error) {
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
 
-	var order models.Order
-	var paidAt sql.NullTime
-	var txHash sql.NullString
-	var creditIssued sql.NullInt64
+	n, err := settleOrderTx(ctx, tx, from, orderID, status, paidAt, txHash, creditIssued, enforceCaps)
+	if err != nil && !errors.Is(err, ErrPromoExhausted) {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return n, err
+}
+
+// settleOrderTx settles the order within tx. ErrPromoExhausted leaves it
+// parked in pending_review, which the caller still commits.
+func settleOrderTx(ctx context.Context, tx pgx.Tx, from []models.OrderStatus, orderID string, status models.OrderStatus, paidAt time.Time, txHash string, creditIssued *int64, enforceCaps bool) (int64, error) {
+	fromStatuses := make([]string, 0, len(from))
+	for _, st := range from {
+		fromStatuses = append(fromStatuses, string(st))
+	}
+	res, err := tx.Exec(ctx, `
+		UPDATE orders
+		SET status=$2, paid_at=$3, tx_hash=$4, credit_issued=$5, updated_at=now()
+		WHERE order_id=$1 AND status = ANY($6)
+	`, orderID, status, paidAt, txHash, creditIssued, fromStatuses)
+	if err != nil {
+		return 0, err
+	}
+	if res.RowsAffected() > 0 && creditIssued != nil {
+		err := redeemPromo(ctx, tx, orderID, enforceCaps)
+		if errors.Is(err, ErrPromoExhausted) {
+			if err := parkPromoExhausted(ctx, tx, orderID, txHash); err != nil {
+				return 0, err
+			}
+			return 0, ErrPromoExhausted
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return res.RowsAffected(), nil
+}
+
+// parkPromoExhausted moves an order just settled in tx to pending_review
+// because its promo code has no redemptions left.
+func parkPromoExhausted(ctx context.Context, tx pgx.Tx, orderID, txHash string) error {
+	if _, err := tx.Exec(ctx, `
+		UPDATE orders SET status='pending_review', credit_issued=NULL, updated_at=now()
+		WHERE order_id=$1
+	`, orderID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ctx, `
+		UPDATE payments SET review_reason=$2
+		WHERE tx_hash=$1 AND review_reason IS NULL
+	`, txHash, ErrPromoExhausted.Error())
+	return err
+}
 
+func (s *Store) GetPayment(ctx context.Context, txHash string) (*models.Payment, error) {
+	row := s.Pool.QueryRow(ctx, `
+		SELECT tx_hash, order_id, COALESCE(from_address, ''), to_address,
+			amount_peaka, denom, height, block_time,
+			fiat_currency, fiat_rate, fiat_amount::text, review_reason, created_at
+		FROM payments WHERE tx_hash=$1
+	`, txHash)
+
+	var p models.Payment
+	var fiatCurrency, fiatRate, fiatAmount, reviewReason sql.NullString
 	err := row.Scan(
-		&order.OrderID,
-		&order.UserID,
-		&order.RecipientAddress,
-		&order.DerivationIndex,
-		&order.CreditRequested,
-		&order.AmountPeaka,
-		&order.Denom,
-		&order.PriceSnapshot,
-		&order.ExpiresAt,
-		&order.Status,
-		&paidAt,
-		&txHash,
-		&creditIssued,
-		&order.CreatedAt,
-		&order.UpdatedAt,
+		&p.TxHash,
+		&p.OrderID,
+		&p.FromAddress,
+		&p.ToAddress,
+		&p.AmountPeaka,
+		&p.Denom,
+		&p.Height,
+		&p.BlockTime,
+		&fiatCurrency,
+		&fiatRate,
+		&fiatAmount,
+		&reviewReason,
+		&p.CreatedAt,
 	)
 	if err != nil {
 		return nil, err
 	}
-
-	if paidAt.Valid {
-		order.PaidAt = &paidAt.Time
+	if fiatCurrency.Valid {
+		p.FiatCurrency = &fiatCurrency.String
 	}
-	if txHash.Valid {
-		order.TxHash = &txHash.String
+	if fiatRate.Valid {
+		p.FiatRate = &fiatRate.String
 	}
-	if creditIssued.Valid {
-		order.CreditIssued = &creditIssued.Int64
+	if fiatAmount.Valid {
+		p.FiatAmount = &fiatAmount.String
 	}
-	return &order, nil
+	if reviewReason.Valid {
+		p.ReviewReason = &reviewReason.String
+	}
+	return &p, nil
+}
+
+// GetWatchedOrderByRecipient returns the order for recipient while it is
+// watched: created, expired at or after expiredSince, or settled at or after
+// settledSince.
+func (s *Store) GetWatchedOrderByRecipient(ctx context.Context, recipient string, expiredSince, settledSince time.Time) (*models.Order, error) {
+	row := s.Pool.QueryRow(ctx, `
+		SELECT `+orderColumns+`
+		FROM orders
+		WHERE recipient_address=$1
+			AND (status='created'
+				OR (status='expired' AND expires_at >= $2)
+				OR (status = ANY($3) AND paid_at >= $4))
+		LIMIT 1
+	`, recipient, expiredSince, settledStatuses, settledSince)
+	return scanOrder(row)
 }
 
 func (s *Store) GetOrderByRecipient(ctx context.Context, recipient string) (*models.Order, error) {
 	row := s.Pool.QueryRow(ctx, `
-		SELECT order_id, user_id, recipient_address, derivation_index,
-			credit_requested, amount_peaka, denom, price_snapshot,
-			expires_at, status, paid_at, tx_hash, credit_issued,
-			created_at, updated_at
+		SELECT `+orderColumns+`
 		FROM orders
 		WHERE recipient_address=$1
 		ORDER BY created_at DESC
 		LIMIT 1
 	`, recipient)
+	return scanOrder(row)
+}
+
+const orderColumns = `order_id, user_id, recipient_address, derivation_index,
+			credit_requested, amount_peaka, denom, price_snapshot,
+			expires_at, status, paid_at, tx_hash, credit_issued,
+			product_id, quantity, product_snapshot, promo_code,
+			created_height, scanned_height, created_at, updated_at`
 
+func scanOrder(row pgx.Row) (*models.Order, error) {
 	var order models.Order
 	var paidAt sql.NullTime
 	var txHash sql.NullString
 	var creditIssued sql.NullInt64
+	var productID sql.NullString
+	var quantity sql.NullInt64
+	var productSnapshot sql.NullString
+	var promoCode sql.NullString
+	var createdHeight sql.NullInt64
+	var scannedHeight sql.NullInt64
 
 	err := row.Scan(
 		&order.OrderID,
@@ -375,6 +492,12 @@ func (s *Store) GetOrderByRecipient(ctx context.Context, recipient string) (*mod
 		&paidAt,
 		&txHash,
 		&creditIssued,
+		&productID,
+		&quantity,
+		&productSnapshot,
+		&promoCode,
+		&createdHeight,
+		&scannedHeight,
 		&order.CreatedAt,
 		&order.UpdatedAt,
 	)
@@ -391,5 +514,36 @@ func (s *Store) GetOrderByRecipient(ctx context.Context, recipient string) (*mod
 	if creditIssued.Valid {
 		order.CreditIssued = &creditIssued.Int64
 	}
+	if productID.Valid {
+		order.ProductID = &productID.String
+	}
+	if quantity.Valid {
+		order.Quantity = &quantity.Int64
+	}
+	if productSnapshot.Valid {
+		order.ProductSnapshot = &productSnapshot.String
+	}
+	if promoCode.Valid {
+		order.PromoCode = &promoCode.String
+	}
+	if createdHeight.Valid {
+		order.CreatedHeight = &createdHeight.Int64
+	}
+	if scannedHeight.Valid {
+		order.ScannedHeight = &scannedHeight.Int64
+	}
 	return &order, nil
 }
+
+func collectOrders(rows pgx.Rows) ([]*models.Order, error) {
+	defer rows.Close()
+	var orders []*models.Order
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	return orders, rows.Err()
+}
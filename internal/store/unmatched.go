@@ -0,0 +1,208 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"DORAPollCredit/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrUnmatchedResolved = errors.New("unmatched payment already resolved")
+	ErrOrderNotPayable   = errors.New("order cannot take a payment")
+)
+
+// MaxDerivationIndex returns the highest derivation index handed out so far,
+// or 0 if none was.
+func (s *Store) MaxDerivationIndex(ctx context.Context) (int64, error) {
+	var idx int64
+	err := s.Pool.QueryRow(ctx, `
+		SELECT CASE WHEN is_called THEN last_value ELSE 0 END
+		FROM order_derivation_index_seq
+	`).Scan(&idx)
+	return idx, err
+}
+
+// InsertUnmatchedPayment records a transfer to a derived address that no
+// watched order could take. It is a no-op when the tx is already recorded
+// for the address or was applied as a payment, and reports whether a row was
+// added.
+func (s *Store) InsertUnmatchedPayment(ctx context.Context, p *models.UnmatchedPayment) (bool, error) {
+	res, err := s.Pool.Exec(ctx, `
+		INSERT INTO unmatched_payments (
+			tx_hash, to_address, derivation_index, order_id, from_address,
+			amount_peaka, denom, height, block_time, reason
+		)
+		SELECT $1,$2,$3,$4,$5,$6,$7,$8,$9,$10
+		WHERE NOT EXISTS (SELECT 1 FROM payments WHERE tx_hash=$1)
+			AND NOT EXISTS (SELECT 1 FROM orphan_payments WHERE tx_hash=$1)
+		ON CONFLICT (tx_hash, to_address) DO NOTHING
+	`,
+		p.TxHash,
+		p.ToAddress,
+		p.DerivationIndex,
+		p.OrderID,
+		p.FromAddress,
+		p.AmountPeaka,
+		p.Denom,
+		p.Height,
+		p.BlockTime,
+		p.Reason,
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.RowsAffected() > 0, nil
+}
+
+func (s *Store) GetUnmatchedPayment(ctx context.Context, id int64) (*models.UnmatchedPayment, error) {
+	row := s.Pool.QueryRow(ctx, `
+		SELECT `+unmatchedColumns+`
+		FROM unmatched_payments WHERE id=$1
+	`, id)
+	return scanUnmatched(row)
+}
+
+// ListUnmatchedPayments lists unmatched payments newest first, optionally
+// filtered by status.
+func (s *Store) ListUnmatchedPayments(ctx context.Context, status string, limit, offset int) ([]*models.UnmatchedPayment, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 200 {
+		limit = 200
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	rows, err := s.Pool.Query(ctx, `
+		SELECT `+unmatchedColumns+`
+		FROM unmatched_payments
+		WHERE $1 = '' OR status = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*models.UnmatchedPayment
+	for rows.Next() {
+		p, err := scanUnmatched(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// ResolveUnmatchedPayment closes an open unmatched payment, recording the
+// order it was applied to, if any.
+func (s *Store) ResolveUnmatchedPayment(ctx context.Context, id int64, status models.UnmatchedStatus, orderID *string, note string) (int64, error) {
+	res, err := s.Pool.Exec(ctx, `
+		UPDATE unmatched_payments
+		SET status=$2, resolved_order_id=$3, note=NULLIF($4, ''), resolved_at=now()
+		WHERE id=$1 AND status='open'
+	`, id, status, orderID, note)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
+
+// ApplyUnmatchedPayment closes open unmatched payment id as applied to
+// payment.OrderID, records payment and settles the order as status, all in
+// one transaction. When payment.ReviewReason is set the order is parked in
+// pending_review instead. ErrPromoExhausted also leaves it parked, committed.
+func (s *Store) ApplyUnmatchedPayment(ctx context.Context, id int64, payment *models.Payment, status models.OrderStatus, creditIssued *int64, note string) error {
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	res, err := tx.Exec(ctx, `
+		UPDATE unmatched_payments
+		SET status=$2, resolved_order_id=$3, note=NULLIF($4, ''), resolved_at=now()
+		WHERE id=$1 AND status='open'
+	`, id, models.UnmatchedApplied, payment.OrderID, note)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return ErrUnmatchedResolved
+	}
+	if err := insertPayment(ctx, tx, payment); err != nil {
+		return err
+	}
+
+	var n int64
+	if payment.ReviewReason != nil {
+		n, err = markPendingReview(ctx, tx, payment.OrderID, payment.BlockTime, payment.TxHash)
+	} else {
+		n, err = settleOrderTx(ctx, tx, []models.OrderStatus{models.OrderCreated, models.OrderExpired},
+			payment.OrderID, status, payment.BlockTime, payment.TxHash, creditIssued, true)
+	}
+	if errors.Is(err, ErrPromoExhausted) {
+		if err := tx.Commit(ctx); err != nil {
+			return err
+		}
+		return ErrPromoExhausted
+	}
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrOrderNotPayable
+	}
+	return tx.Commit(ctx)
+}
+
+const unmatchedColumns = `id, tx_hash, to_address, derivation_index, order_id,
+			COALESCE(from_address, ''), amount_peaka, denom, height, block_time,
+			reason, status, resolved_order_id, note, resolved_at, created_at`
+
+func scanUnmatched(row pgx.Row) (*models.UnmatchedPayment, error) {
+	var p models.UnmatchedPayment
+	var orderID, resolvedOrderID, note sql.NullString
+	var resolvedAt sql.NullTime
+	err := row.Scan(
+		&p.ID,
+		&p.TxHash,
+		&p.ToAddress,
+		&p.DerivationIndex,
+		&orderID,
+		&p.FromAddress,
+		&p.AmountPeaka,
+		&p.Denom,
+		&p.Height,
+		&p.BlockTime,
+		&p.Reason,
+		&p.Status,
+		&resolvedOrderID,
+		&note,
+		&resolvedAt,
+		&p.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if orderID.Valid {
+		p.OrderID = &orderID.String
+	}
+	if resolvedOrderID.Valid {
+		p.ResolvedOrderID = &resolvedOrderID.String
+	}
+	if note.Valid {
+		p.Note = &note.String
+	}
+	if resolvedAt.Valid {
+		p.ResolvedAt = &resolvedAt.Time
+	}
+	return &p, nil
+}
@@ -0,0 +1,83 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"DORAPollCredit/internal/chain"
+	"DORAPollCredit/internal/models"
+	"DORAPollCredit/internal/payments"
+)
+
+const (
+	ScanModeTxSearch = "tx_search"
+	ScanModeBlock    = "block"
+)
+
+// scanBlocks walks every height in from..to once, matching transfers in the
+// configured denom against the recipient addresses of pending orders and of
+// expired or settled orders still in their watch windows. It returns the
+// last height that was fully applied; a block that cannot be fetched or whose
+// payment cannot be applied stops the walk just below it.
+func (w *Worker) scanBlocks(ctx context.Context, from, to int64) (int64, error) {
+	orders, err := w.Store.ListPendingOrders(ctx)
+	if err != nil {
+		return from - 1, err
+	}
+	// Every block is fetched anyway, so expired and settled orders in their
+	// watch windows cost nothing extra here.
+	expired, settled, err := w.lateOrders(ctx)
+	if err != nil {
+		return from - 1, err
+	}
+	orders = append(append(orders, expired...), settled...)
+	log.Printf("block scan range=%d..%d watched=%d", from, to, len(orders))
+	if len(orders) == 0 && w.Deriver.XPub == "" {
+		return to, nil
+	}
+
+	watched := make(map[string]*models.Order, len(orders))
+	for _, order := range orders {
+		watched[order.RecipientAddress] = order
+	}
+
+	for h := from; h <= to; h++ {
+		txs, blockEvents, err := chain.BlockTxs(ctx, w.Chain, h)
+		if err != nil {
+			return h - 1, fmt.Errorf("height %d: %w", h, err)
+		}
+		// Block-level transfers have no tx hash to settle against; surface
+		// them so they can be reconciled by hand.
+		for _, t := range payments.ExtractTransfers(blockEvents, w.Denom) {
+			if order, ok := watched[t.Recipient]; ok {
+				log.Printf("block-level transfer to order %s height=%d amount=%s", order.OrderID, h, t.Amount)
+			}
+		}
+		// The rest of the block is still applied; a retry of the block
+		// skips or re-records the payments already handled.
+		var failed error
+		for _, tx := range txs {
+			if tx.Code != 0 {
+				continue
+			}
+			for _, t := range payments.ExtractTransfers(tx.Events, w.Denom) {
+				order, ok := watched[t.Recipient]
+				if !ok {
+					if err := w.recordUnmatched(ctx, tx, t); err != nil {
+						failed = fmt.Errorf("height %d: record unmatched tx=%s: %w", h, tx.Hash, err)
+					}
+					continue
+				}
+				if err := w.handleTransfer(ctx, order, tx, t.Amount, t.Sender); err != nil {
+					log.Printf("apply payment failed order=%s tx=%s: %v", order.OrderID, tx.Hash, err)
+					failed = fmt.Errorf("height %d: apply payment tx=%s: %w", h, tx.Hash, err)
+				}
+			}
+		}
+		if failed != nil {
+			return h - 1, failed
+		}
+	}
+	return to, nil
+}
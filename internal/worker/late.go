@@ -0,0 +1,134 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"DORAPollCredit/internal/chain"
+	"DORAPollCredit/internal/models"
+	"DORAPollCredit/internal/payments"
+)
+
+const (
+	defaultLateScanInterval = 5 * time.Minute
+	defaultOrphanWatch      = 30 * 24 * time.Hour
+)
+
+// expiredSince is the oldest expires_at still watched for late payments.
+// With no LateWatch window it is now, which excludes every expired order.
+func (w *Worker) expiredSince() time.Time {
+	now := time.Now().UTC()
+	if w.LateWatch <= 0 {
+		return now
+	}
+	return now.Add(-w.LateWatch)
+}
+
+// settledSince is the oldest paid_at still watched for orphan payments. It
+// has its own window, so settled orders are watched even with LateWatch off.
+func (w *Worker) settledSince() time.Time {
+	watch := w.OrphanWatch
+	if watch <= 0 {
+		watch = defaultOrphanWatch
+	}
+	return time.Now().UTC().Add(-watch)
+}
+
+// lateOrders lists the expired orders inside the LateWatch window and the
+// settled orders inside the OrphanWatch window.
+func (w *Worker) lateOrders(ctx context.Context) (expired, settled []*models.Order, err error) {
+	if w.LateWatch > 0 {
+		if expired, err = w.Store.ListExpiredOrders(ctx, w.expiredSince()); err != nil {
+			return nil, nil, err
+		}
+	}
+	if settled, err = w.Store.ListSettledOrders(ctx, w.settledSince()); err != nil {
+		return nil, nil, err
+	}
+	return expired, settled, nil
+}
+
+// scanLate advances the cursors of expired orders in the LateWatch window
+// and settled orders in the OrphanWatch window at most once per
+// LateScanInterval, so they cost far less than pending orders. Late payments
+// settle through the normal logic; transfers to settled orders become orphan
+// payments, and transfers to lookahead addresses, which share one cursor,
+// unmatched payments. Block mode covers all of these in scanBlocks instead.
+func (w *Worker) scanLate(ctx context.Context, to int64) {
+	if w.ScanMode == ScanModeBlock {
+		return
+	}
+	interval := w.LateScanInterval
+	if interval <= 0 {
+		interval = defaultLateScanInterval
+	}
+	if time.Since(w.lastLate) < interval {
+		return
+	}
+
+	orders, settled, err := w.lateOrders(ctx)
+	if err != nil {
+		log.Printf("list late orders failed: %v", err)
+		return
+	}
+	log.Printf("late scan to=%d expired=%d settled=%d", to, len(orders), len(settled))
+	orders = append(orders, settled...)
+	for _, order := range orders {
+		if err := w.scanOrderFromCursor(ctx, order, to); err != nil {
+			log.Printf("late scan order %s failed: %v", order.OrderID, err)
+		}
+	}
+
+	if err := w.scanUnassigned(ctx, to); err != nil {
+		log.Printf("late scan lookahead addresses failed: %v", err)
+		return
+	}
+	w.lastLate = time.Now()
+}
+
+// scanUnassigned records anything sent to lookahead addresses, which have no
+// order yet, as unmatched. Only the LookaheadScanBudget lowest lookahead
+// addresses are searched, two tx_search queries each, and they share one
+// cursor; transfers to the rest, or to orders outside their watch windows,
+// are only found by WS or block mode, which match every registry address at
+// no extra cost. With WS disabled in tx_search mode they are never scanned.
+func (w *Worker) scanUnassigned(ctx context.Context, to int64) error {
+	budget := w.LookaheadScanBudget
+	if budget <= 0 {
+		budget = defaultLookaheadScanBudget
+	}
+	addrs := w.registry.unassigned(budget)
+	if len(addrs) == 0 {
+		return nil
+	}
+	last, err := w.Store.GetLateScanHeight(ctx)
+	if err != nil {
+		return err
+	}
+	var scanned *int64
+	var synced int64
+	if last > 0 {
+		scanned = &last
+	} else if synced, err = w.Store.GetSyncHeight(ctx); err != nil {
+		return err
+	}
+	from := cursorStart(scanned, nil, synced, w.StartHeight)
+	if from > to {
+		return nil
+	}
+	done := to
+	for _, addr := range addrs {
+		failed, err := w.scanAddress(ctx, addr, w.Denom, from, to, func(tx chain.Tx, t payments.Transfer) error {
+			return w.recordUnmatched(ctx, tx, t)
+		})
+		if err != nil {
+			return err
+		}
+		done = min(done, scanLimit(to, failed))
+	}
+	if done < from {
+		return nil
+	}
+	return w.Store.SetLateScanHeight(ctx, done)
+}
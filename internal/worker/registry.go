@@ -0,0 +1,166 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+
+	"DORAPollCredit/internal/chain"
+	"DORAPollCredit/internal/models"
+	"DORAPollCredit/internal/payments"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	defaultAddressLookahead = 100
+	// defaultLookaheadScanBudget bounds how many lookahead addresses each
+	// late scan searches in tx_search mode; each costs two tx_search queries
+	// per page.
+	defaultLookaheadScanBudget = 20
+)
+
+// addressRegistry maps every address derived so far, plus a lookahead, to its
+// derivation index, so transfers to our addresses are noticed even when no
+// watched order matches.
+type addressRegistry struct {
+	mu      sync.RWMutex
+	byAddr  map[string]int64
+	next    int64
+	ordered int64
+}
+
+func newAddressRegistry() *addressRegistry {
+	return &addressRegistry{byAddr: map[string]int64{}, next: 1}
+}
+
+func (r *addressRegistry) lookup(addr string) (int64, bool) {
+	if r == nil {
+		return 0, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	idx, ok := r.byAddr[addr]
+	return idx, ok
+}
+
+// unassigned returns up to limit lookahead addresses beyond the last index
+// handed out to an order, lowest index first, since those are handed out
+// next.
+func (r *addressRegistry) unassigned(limit int) []string {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	type entry struct {
+		addr string
+		idx  int64
+	}
+	var pending []entry
+	for addr, idx := range r.byAddr {
+		if idx > r.ordered {
+			pending = append(pending, entry{addr, idx})
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].idx < pending[j].idx })
+	out := make([]string, 0, min(limit, len(pending)))
+	for _, e := range pending {
+		if len(out) == limit {
+			break
+		}
+		out = append(out, e.addr)
+	}
+	return out
+}
+
+// refreshRegistry derives the addresses up to the highest handed-out index
+// plus AddressLookahead. It is a no-op without an xpub.
+func (w *Worker) refreshRegistry(ctx context.Context) {
+	if w.Deriver.XPub == "" || w.registry == nil {
+		return
+	}
+	ordered, err := w.Store.MaxDerivationIndex(ctx)
+	if err != nil {
+		log.Printf("address registry: max derivation index failed: %v", err)
+		return
+	}
+	lookahead := w.AddressLookahead
+	if lookahead <= 0 {
+		lookahead = defaultAddressLookahead
+	}
+	top := ordered + lookahead
+
+	r := w.registry
+	r.mu.RLock()
+	next := r.next
+	r.mu.RUnlock()
+
+	derived := make(map[string]int64)
+	for idx := next; idx <= top; idx++ {
+		addr, err := w.Deriver.Derive(uint32(idx))
+		if err != nil {
+			log.Printf("address registry: derive %d failed: %v", idx, err)
+			return
+		}
+		derived[addr] = idx
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for addr, idx := range derived {
+		r.byAddr[addr] = idx
+	}
+	r.next = max(r.next, top+1)
+	r.ordered = ordered
+}
+
+// recordUnmatched stores a transfer to a derived address that no watched
+// order took, with the reason. Transfers to foreign addresses are ignored.
+func (w *Worker) recordUnmatched(ctx context.Context, tx chain.Tx, t payments.Transfer) error {
+	idx, ok := w.registry.lookup(t.Recipient)
+	if !ok {
+		return nil
+	}
+	p := &models.UnmatchedPayment{
+		TxHash:          tx.Hash,
+		ToAddress:       t.Recipient,
+		DerivationIndex: idx,
+		FromAddress:     t.Sender,
+		AmountPeaka:     t.Amount,
+		Denom:           w.Denom,
+		Height:          tx.Height,
+		BlockTime:       tx.Timestamp,
+		Reason:          "no_order",
+	}
+	order, err := w.Store.GetOrderByRecipient(ctx, t.Recipient)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+	case err != nil:
+		log.Printf("unmatched get order failed: %v", err)
+		return err
+	default:
+		p.OrderID = &order.OrderID
+		p.Reason = "order_" + string(order.Status)
+	}
+	if p.BlockTime.IsZero() {
+		txs := []chain.Tx{tx}
+		if err := chain.FillBlockTimes(ctx, w.Chain, txs); err != nil {
+			log.Printf("unmatched block time tx=%s failed: %v", tx.Hash, err)
+			return err
+		}
+		p.BlockTime = txs[0].Timestamp
+	}
+
+	added, err := w.Store.InsertUnmatchedPayment(ctx, p)
+	if err != nil {
+		log.Printf("record unmatched payment tx=%s failed: %v", tx.Hash, err)
+		return err
+	}
+	if added {
+		log.Printf("unmatched payment to %s (index %d) tx=%s amount=%s reason=%s", t.Recipient, idx, tx.Hash, t.Amount, p.Reason)
+	}
+	return nil
+}
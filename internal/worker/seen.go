@@ -0,0 +1,98 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"DORAPollCredit/internal/chain"
+	"DORAPollCredit/internal/payments"
+)
+
+// seenPaymentTTL bounds how long a seen tx that cannot be fetched is retried;
+// the polling scan still settles it if it exists.
+const seenPaymentTTL = 24 * time.Hour
+
+// finalizeSeen settles WS-seen payments that are now at or below confirmed,
+// and records the seen transfers no order took as unmatched. Each tx is
+// fetched again so the events and block time are the node's confirmed view
+// rather than the WS copy.
+func (w *Worker) finalizeSeen(ctx context.Context, confirmed int64) {
+	seen, err := w.Store.ListSeenPayments(ctx, confirmed)
+	if err != nil {
+		log.Printf("list seen payments failed: %v", err)
+		return
+	}
+	for _, p := range seen {
+		tx, err := w.Chain.TxByHash(ctx, p.TxHash)
+		if err != nil {
+			log.Printf("finalize seen tx=%s to=%s failed: %v", p.TxHash, p.ToAddress, err)
+			continue
+		}
+		txs := []chain.Tx{*tx}
+		if err := chain.FillBlockTimes(ctx, w.Chain, txs); err != nil {
+			log.Printf("finalize seen block time tx=%s failed: %v", p.TxHash, err)
+			continue
+		}
+		var ok bool
+		if p.OrderID == "" {
+			ok = w.finalizeSeenUnmatched(ctx, txs[0], p.ToAddress)
+		} else {
+			ok = w.finalizeSeenOrder(ctx, txs[0], p.OrderID)
+		}
+		if !ok {
+			continue
+		}
+		if err := w.Store.DeleteSeenPayment(ctx, p.TxHash, p.ToAddress); err != nil {
+			log.Printf("delete seen payment tx=%s failed: %v", p.TxHash, err)
+		}
+	}
+
+	if n, err := w.Store.PruneSeenPayments(ctx, time.Now().Add(-seenPaymentTTL)); err != nil {
+		log.Printf("prune seen payments failed: %v", err)
+	} else if n > 0 {
+		log.Printf("pruned %d seen payments that never finalized", n)
+	}
+}
+
+// finalizeSeenOrder applies the confirmed tx's transfers to the order and
+// reports whether all of them were handled.
+func (w *Worker) finalizeSeenOrder(ctx context.Context, tx chain.Tx, orderID string) bool {
+	order, err := w.Store.GetOrder(ctx, orderID)
+	if err != nil {
+		log.Printf("finalize seen get order %s failed: %v", orderID, err)
+		return false
+	}
+	if tx.Code != 0 {
+		return true
+	}
+	applied := true
+	for _, t := range payments.ExtractTransfers(tx.Events, order.Denom) {
+		if t.Recipient != order.RecipientAddress {
+			continue
+		}
+		if err := w.handleTransfer(ctx, order, tx, t.Amount, t.Sender); err != nil {
+			log.Printf("apply payment failed order=%s tx=%s: %v", order.OrderID, tx.Hash, err)
+			applied = false
+		}
+	}
+	return applied
+}
+
+// finalizeSeenUnmatched records the confirmed tx's transfers to addr as
+// unmatched and reports whether all of them were recorded.
+func (w *Worker) finalizeSeenUnmatched(ctx context.Context, tx chain.Tx, addr string) bool {
+	if tx.Code != 0 {
+		return true
+	}
+	recorded := true
+	for _, t := range payments.ExtractTransfers(tx.Events, w.Denom) {
+		if t.Recipient != addr {
+			continue
+		}
+		if err := w.recordUnmatched(ctx, tx, t); err != nil {
+			recorded = false
+		}
+	}
+	return recorded
+}
@@ -0,0 +1,194 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"DORAPollCredit/internal/chain"
+)
+
+const (
+	firehoseQuery = "tm.event='Tx'"
+	newBlockQuery = "tm.event='NewBlock'"
+	// CometBFT's default rpc.max_subscriptions_per_client.
+	defaultWSMaxSubscriptions = 5
+	defaultWSSubscriptionSync = 5 * time.Second
+)
+
+// wsSubscriptions keeps one connection subscribed to new blocks and to
+// exactly the watched recipient addresses, or to every tx once they no longer
+// fit within the node's per-client subscription limit. It lives as long as
+// its connection, so a new connection starts with targeted queries again.
+type wsSubscriptions struct {
+	client   *chain.WSClient
+	endpoint string
+	max      int
+
+	// limited is signalled by the reader when the node rejects a
+	// subscription for exceeding its limit, which may be lower than max.
+	limited       chan struct{}
+	forceFirehose bool
+	firehose      bool
+	active        map[string]struct{}
+}
+
+func newWSSubscriptions(client *chain.WSClient, endpoint string, max int) *wsSubscriptions {
+	if max <= 0 {
+		max = defaultWSMaxSubscriptions
+	}
+	return &wsSubscriptions{
+		client:   client,
+		endpoint: endpoint,
+		max:      max,
+		limited:  make(chan struct{}, 1),
+		active:   map[string]struct{}{},
+	}
+}
+
+// recipientQueries matches transfers to addr under both keys scanAddress
+// searches, so a transfer the node reports only as coin_received is seen too.
+func recipientQueries(addr string) []string {
+	return []string{
+		firehoseQuery + " AND transfer.recipient='" + addr + "'",
+		firehoseQuery + " AND coin_received.receiver='" + addr + "'",
+	}
+}
+
+// limitReached switches the connection to the firehose alone on its next
+// reconcile. It never blocks the reader.
+func (s *wsSubscriptions) limitReached() {
+	select {
+	case s.limited <- struct{}{}:
+	default:
+	}
+}
+
+// run reconciles the subscriptions against the watched orders every interval
+// until ctx is done. A write error closes the client so the reader reconnects.
+func (s *wsSubscriptions) run(ctx context.Context, w *Worker, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultWSSubscriptionSync
+	}
+	if err := s.client.Subscribe(ctx, newBlockQuery); err != nil {
+		log.Printf("ws subscribe NewBlock failed (%s): %v", s.endpoint, err)
+		s.client.Close()
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		addrs, err := w.watchedAddresses(ctx)
+		if err != nil {
+			log.Printf("ws watched addresses failed: %v", err)
+		} else if err := s.reconcile(ctx, addrs); err != nil {
+			log.Printf("ws subscription update failed (%s): %v", s.endpoint, err)
+			s.client.Close()
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.limited:
+			if !s.forceFirehose {
+				log.Printf("ws %s: subscription limit reached, using firehose until reconnect", s.endpoint)
+				s.forceFirehose = true
+			}
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *wsSubscriptions) reconcile(ctx context.Context, addrs []string) error {
+	// One slot holds NewBlock and one stays free so switching back from the
+	// firehose never exceeds the limit.
+	if s.forceFirehose || 2*len(addrs) > s.max-2 {
+		return s.useFirehose(ctx)
+	}
+
+	want := make(map[string]struct{}, 2*len(addrs))
+	for _, addr := range addrs {
+		for _, q := range recipientQueries(addr) {
+			want[q] = struct{}{}
+		}
+	}
+	for q := range s.active {
+		if _, ok := want[q]; ok {
+			continue
+		}
+		if err := s.client.Unsubscribe(ctx, q); err != nil {
+			return err
+		}
+		delete(s.active, q)
+	}
+	for q := range want {
+		if _, ok := s.active[q]; ok {
+			continue
+		}
+		if err := s.client.Subscribe(ctx, q); err != nil {
+			return err
+		}
+		s.active[q] = struct{}{}
+	}
+	if s.firehose {
+		if err := s.client.Unsubscribe(ctx, firehoseQuery); err != nil {
+			return err
+		}
+		s.firehose = false
+		log.Printf("ws %s: targeted subscriptions for %d addresses", s.endpoint, len(addrs))
+	}
+	return nil
+}
+
+// useFirehose subscribes to every tx before dropping the targeted queries so
+// no event is missed in between. Once the node has rejected a subscription
+// there is no slot to spare, so the targeted queries are dropped first.
+func (s *wsSubscriptions) useFirehose(ctx context.Context) error {
+	if s.firehose {
+		return nil
+	}
+	if s.forceFirehose {
+		if err := s.dropTargeted(ctx); err != nil {
+			return err
+		}
+	}
+	if err := s.client.Subscribe(ctx, firehoseQuery); err != nil {
+		return err
+	}
+	s.firehose = true
+	if err := s.dropTargeted(ctx); err != nil {
+		return err
+	}
+	log.Printf("ws %s: falling back to firehose subscription", s.endpoint)
+	return nil
+}
+
+func (s *wsSubscriptions) dropTargeted(ctx context.Context) error {
+	for q := range s.active {
+		if err := s.client.Unsubscribe(ctx, q); err != nil {
+			return err
+		}
+		delete(s.active, q)
+	}
+	return nil
+}
+
+// watchedAddresses lists the recipients handleWSTx would match: pending
+// orders and expired or settled ones within their watch windows.
+func (w *Worker) watchedAddresses(ctx context.Context) ([]string, error) {
+	orders, err := w.Store.ListPendingOrders(ctx)
+	if err != nil {
+		return nil, err
+	}
+	expired, settled, err := w.lateOrders(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orders = append(append(orders, expired...), settled...)
+	addrs := make([]string, 0, len(orders))
+	for _, order := range orders {
+		addrs = append(addrs, order.RecipientAddress)
+	}
+	return addrs, nil
+}
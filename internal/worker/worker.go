@@ -2,16 +2,26 @@ package worker
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"DORAPollCredit/internal/chain"
 	"DORAPollCredit/internal/models"
 	"DORAPollCredit/internal/payments"
+	"DORAPollCredit/internal/pricing"
 	"DORAPollCredit/internal/store"
 )
 
+// syncMargin is how far below the global sync height a scan without its own
+// starting point begins, covering blocks produced while that tick ran.
+const syncMargin = 100
+
 type Worker struct {
 	Store               *store.Store
 	Chain               chain.Client
@@ -19,85 +29,219 @@ type Worker struct {
 	Decimals            int
 	ConfirmDepth        int64
 	StartHeight         int64
-	RewindBlocks        int64
 	MaxBlocksPerTick    int64
 	PerPage             int
 	Interval            time.Duration
+	LateWatch           time.Duration
+	OrphanWatch         time.Duration
+	LateScanInterval    time.Duration
 	WSEndpoints         []string
 	WSBackfillBlocks    int64
+	WSBackfillMax       int64
+	WSBackfillChunk     int64
 	WSFailoverThreshold int
+	WSRedundancy        int
+	WSMaxSubscriptions  int
+	WSSubscriptionSync  time.Duration
+	WSPingInterval      time.Duration
+	WSReadTimeout       time.Duration
+	Pricing             pricing.Service
+	ScanMode            string
+	Verify              payments.Verification
+	AttrEncoding        chain.AttrEncoding
+	Deriver             chain.AddressDeriver
+	AddressLookahead    int64
+	LookaheadScanBudget int
+
+	// heads carries NewBlock heights from the WS readers to Run.
+	heads chan int64
+	// wsHigh is the highest height seen on any WS stream; backfilled is the
+	// highest height a gap backfill has covered.
+	wsHigh     atomic.Int64
+	backfilled atomic.Int64
+	// backfillMu guards the pending backfill request: whether one is
+	// waiting, its lowest start (0 for the initial blocks) and whether the
+	// backfill goroutine is running.
+	backfillMu      sync.Mutex
+	backfillPending bool
+	backfillFrom    int64
+	backfillRunning bool
+	lastLate        time.Time
+	registry        *addressRegistry
 }
 
+// Run syncs whenever a NewBlock event makes a new height confirmed, scanning
+// up to that height without asking a node for the tip. Heads that arrive
+// during a sync are coalesced into one. The Interval ticker only syncs while
+// no NewBlock has arrived for a whole interval, i.e. when every WS
+// connection is down.
 func (w *Worker) Run(ctx context.Context) {
+	w.heads = make(chan int64, 64)
+	w.registry = newAddressRegistry()
+	w.refreshRegistry(ctx)
 	go w.RunWS(ctx)
 	ticker := time.NewTicker(w.Interval)
 	defer ticker.Stop()
 
+	if err := w.SyncOnce(ctx); err != nil {
+		log.Printf("sync error: %v", err)
+	}
+	var lastHead time.Time
+	var confirmed, persisted int64
 	for {
-		if err := w.SyncOnce(ctx); err != nil {
-			log.Printf("sync error: %v", err)
-		}
 		select {
 		case <-ctx.Done():
 			return
+		case height := <-w.heads:
+			lastHead = time.Now()
+			height = w.latestHead(height)
+			if height > persisted {
+				if err := w.Store.SetWSHeight(ctx, height); err != nil {
+					log.Printf("save ws height failed: %v", err)
+				} else {
+					persisted = height
+				}
+			}
+			if height-w.ConfirmDepth > confirmed {
+				confirmed = height - w.ConfirmDepth
+				if err := w.syncTo(ctx, confirmed); err != nil {
+					log.Printf("sync error: %v", err)
+				}
+			}
 		case <-ticker.C:
+			if time.Since(lastHead) < w.Interval {
+				continue
+			}
+			if err := w.SyncOnce(ctx); err != nil {
+				log.Printf("sync error: %v", err)
+			}
+		}
+	}
+}
+
+// latestHead returns the highest of height and any heads already queued.
+func (w *Worker) latestHead(height int64) int64 {
+	for {
+		select {
+		case h := <-w.heads:
+			height = max(height, h)
+		default:
+			return height
 		}
 	}
 }
 
+// SyncOnce scans up to the confirmed tip.
 func (w *Worker) SyncOnce(ctx context.Context) error {
 	latest, err := w.Chain.LatestHeight(ctx)
 	if err != nil {
 		return err
 	}
-	to := latest - w.ConfirmDepth
+	return w.syncTo(ctx, latest-w.ConfirmDepth)
+}
+
+// syncTo scans up to the confirmed height to. In tx_search mode every watched
+// order resumes from its own cursor; block mode walks the global cursor.
+func (w *Worker) syncTo(ctx context.Context, to int64) error {
 	if to <= 0 {
 		return nil
 	}
+	w.refreshRegistry(ctx)
+	w.finalizeSeen(ctx, to)
+
+	// Orders expire only after the pending scan, so a payment confirmed in
+	// the tick its order expires is still matched while the order is pending.
+	if w.ScanMode == ScanModeBlock {
+		scanErr := w.syncBlocks(ctx, to)
+		if err := w.Store.MarkExpired(ctx, time.Now().UTC()); err != nil {
+			return err
+		}
+		return scanErr
+	}
+
+	if err := w.scanPending(ctx, to); err != nil {
+		return err
+	}
+	if err := w.Store.MarkExpired(ctx, time.Now().UTC()); err != nil {
+		return err
+	}
+	if err := w.Store.SetSyncHeight(ctx, to); err != nil {
+		return err
+	}
+	w.scanLate(ctx, to)
+	return nil
+}
 
+// syncBlocks walks the global block cursor towards to, at most
+// MaxBlocksPerTick blocks, and saves how far it got.
+func (w *Worker) syncBlocks(ctx context.Context, to int64) error {
 	last, err := w.Store.GetSyncHeight(ctx)
 	if err != nil {
 		return err
 	}
 	from := last + 1
 	if last == 0 {
-		if w.StartHeight > 0 {
-			from = w.StartHeight
-		} else {
-			from = 1
-		}
-	} else if w.RewindBlocks > 0 {
-		if last > w.RewindBlocks {
-			from = last - w.RewindBlocks + 1
-		} else {
-			from = 1
-		}
+		from = max(w.StartHeight, 1)
 	}
 	if from > to {
 		return nil
 	}
 	if w.MaxBlocksPerTick > 0 {
-		limitTo := from + w.MaxBlocksPerTick - 1
-		if limitTo < to {
-			to = limitTo
+		to = min(to, from+w.MaxBlocksPerTick-1)
+	}
+	done, scanErr := w.scanBlocks(ctx, from, to)
+	if done >= from {
+		if err := w.Store.SetSyncHeight(ctx, done); err != nil {
+			return err
 		}
 	}
+	return scanErr
+}
 
-	if err := w.Store.MarkExpired(ctx, time.Now().UTC()); err != nil {
-		return err
+// requestBackfill queues a gap backfill from the current WS high-water mark
+// and starts the backfill goroutine unless it is already running, so the
+// caller's read loop is not held up and reconnects never stack backfills.
+// The mark itself is rescanned: its NewBlock arrives before the block's Tx
+// events, so a drop in between loses them.
+func (w *Worker) requestBackfill(ctx context.Context, initial int64) {
+	from := w.wsHigh.Load()
+	w.backfillMu.Lock()
+	defer w.backfillMu.Unlock()
+	if !w.backfillPending || from < w.backfillFrom {
+		w.backfillFrom = from
 	}
-
-	if err := w.scanRange(ctx, from, to); err != nil {
-		return err
+	w.backfillPending = true
+	if w.backfillRunning {
+		return
 	}
-
-	return w.Store.SetSyncHeight(ctx, to)
+	w.backfillRunning = true
+	go w.runBackfill(ctx, initial)
 }
 
-func (w *Worker) BackfillRecent(ctx context.Context, blocks int64) {
-	if blocks <= 0 {
-		return
+// runBackfill serves backfill requests until none is pending; requests made
+// while a backfill runs are merged into one follow-up pass.
+func (w *Worker) runBackfill(ctx context.Context, initial int64) {
+	for {
+		w.backfillMu.Lock()
+		if !w.backfillPending || ctx.Err() != nil {
+			w.backfillRunning = false
+			w.backfillMu.Unlock()
+			return
+		}
+		from := w.backfillFrom
+		w.backfillPending = false
+		w.backfillMu.Unlock()
+
+		w.backfillGap(ctx, from, initial)
 	}
+}
+
+// backfillGap scans the confirmed blocks no WS stream has observed, from
+// from (or the last initial blocks when no height was ever seen) up to the
+// confirmed tip, skipping what an earlier backfill covered. At most
+// WSBackfillMax blocks are scanned, in chunks of WSBackfillChunk; anything
+// older is left to the sync loop.
+func (w *Worker) backfillGap(ctx context.Context, from, initial int64) {
 	latest, err := w.Chain.LatestHeight(ctx)
 	if err != nil {
 		log.Printf("ws backfill latest height failed: %v", err)
@@ -107,17 +251,42 @@ func (w *Worker) BackfillRecent(ctx context.Context, blocks int64) {
 	if to <= 0 {
 		return
 	}
-	from := to - blocks + 1
-	if from < 1 {
-		from = 1
+	if from > 0 {
+		from = max(from, w.backfilled.Load()+1)
+	} else if initial > 0 {
+		from = to - initial + 1
+	} else {
+		return
+	}
+	from = max(from, 1)
+	if from > to {
+		return
 	}
+	if limit := w.WSBackfillMax; limit > 0 && to-from+1 > limit {
+		log.Printf("ws gap %d..%d exceeds %d blocks, backfilling the newest only", from, to, limit)
+		from = to - limit + 1
+	}
+	chunk := w.WSBackfillChunk
+	if chunk <= 0 {
+		chunk = to - from + 1
+	}
+
 	log.Printf("ws backfill range=%d..%d", from, to)
-	if err := w.scanRange(ctx, from, to); err != nil {
-		log.Printf("ws backfill failed: %v", err)
+	for start := from; start <= to; start += chunk {
+		end := min(start+chunk-1, to)
+		if err := w.scanRange(ctx, start, end); err != nil {
+			log.Printf("ws backfill %d..%d failed: %v", start, end, err)
+			return
+		}
+		w.backfilled.Store(end)
 	}
 }
 
 func (w *Worker) scanRange(ctx context.Context, from, to int64) error {
+	if w.ScanMode == ScanModeBlock {
+		_, err := w.scanBlocks(ctx, from, to)
+		return err
+	}
 	orders, err := w.Store.ListPendingOrders(ctx)
 	if err != nil {
 		return err
@@ -132,16 +301,120 @@ func (w *Worker) scanRange(ctx context.Context, from, to int64) error {
 	}
 	log.Printf("sync range=%d..%d pending=%d ids=%s", from, to, len(orders), strings.Join(ids, ","))
 	for _, order := range orders {
-		if err := w.scanOrder(ctx, order, from, to); err != nil {
+		if _, err := w.scanOrder(ctx, order, from, to); err != nil {
 			log.Printf("scan order %s failed: %v", order.OrderID, err)
 		}
 	}
 	return nil
 }
 
-func (w *Worker) scanOrder(ctx context.Context, order *models.Order, from, to int64) error {
+// scanPending advances every pending order's cursor towards to.
+func (w *Worker) scanPending(ctx context.Context, to int64) error {
+	orders, err := w.Store.ListPendingOrders(ctx)
+	if err != nil {
+		return err
+	}
+	if len(orders) == 0 {
+		log.Printf("sync to=%d pending=0", to)
+		return nil
+	}
+	ids := make([]string, 0, len(orders))
+	for _, order := range orders {
+		ids = append(ids, order.OrderID)
+	}
+	log.Printf("sync to=%d pending=%d ids=%s", to, len(orders), strings.Join(ids, ","))
+	for _, order := range orders {
+		if err := w.scanOrderFromCursor(ctx, order, to); err != nil {
+			log.Printf("scan order %s failed: %v", order.OrderID, err)
+		}
+	}
+	return nil
+}
+
+// scanOrderFromCursor scans order from the height after its cursor up to to,
+// at most MaxBlocksPerTick blocks, and then advances the cursor. The cursor
+// stops below the first height whose payment could not be applied, so the
+// next tick retries it.
+func (w *Worker) scanOrderFromCursor(ctx context.Context, order *models.Order, to int64) error {
+	var synced int64
+	if order.ScannedHeight == nil && order.CreatedHeight == nil {
+		var err error
+		if synced, err = w.Store.GetSyncHeight(ctx); err != nil {
+			return err
+		}
+	}
+	from := cursorStart(order.ScannedHeight, order.CreatedHeight, synced, w.StartHeight)
+	if from > to {
+		return nil
+	}
+	if w.MaxBlocksPerTick > 0 {
+		to = min(to, from+w.MaxBlocksPerTick-1)
+	}
+	failed, err := w.scanOrder(ctx, order, from, to)
+	if err != nil {
+		return err
+	}
+	if done := scanLimit(to, failed); done >= from {
+		if err := w.Store.SetOrderScannedHeight(ctx, order.OrderID, done); err != nil {
+			return err
+		}
+		order.ScannedHeight = &done
+	}
+	if failed > 0 {
+		return fmt.Errorf("payment at height %d not applied, retrying next tick", failed)
+	}
+	return nil
+}
+
+// cursorStart returns the first height to scan: the one after the cursor,
+// else the creation height. Without either, the order was created after the
+// last sync tick, so it starts syncMargin blocks below the global sync
+// height; only a worker that has never synced starts at startHeight.
+func cursorStart(scanned, created *int64, synced, startHeight int64) int64 {
+	switch {
+	case scanned != nil:
+		return *scanned + 1
+	case created != nil:
+		return *created
+	case synced > 0:
+		return max(synced-syncMargin, 1)
+	default:
+		return max(startHeight, 1)
+	}
+}
+
+// scanOrder settles the transfers to the order's address within from..to,
+// oldest first. It returns the lowest height whose
+// transfer could not be applied, or 0.
+func (w *Worker) scanOrder(ctx context.Context, order *models.Order, from, to int64) (int64, error) {
+	return w.scanAddress(ctx, order.RecipientAddress, order.Denom, from, to, func(tx chain.Tx, t payments.Transfer) error {
+		if err := w.handleTransfer(ctx, order, tx, t.Amount, t.Sender); err != nil {
+			log.Printf("apply payment failed order=%s tx=%s: %v", order.OrderID, tx.Hash, err)
+			return err
+		}
+		return nil
+	})
+}
+
+// scanLimit returns the height a cursor may advance to after scanning up to
+// to, given the lowest height whose handling failed (0 when none did).
+func scanLimit(to, failed int64) int64 {
+	if failed > 0 && failed <= to {
+		return failed - 1
+	}
+	return to
+}
+
+// scanAddress calls handle for each successful transfer of denom to addr
+// within from..to, oldest first, and returns the height at which handle
+// first failed, or 0; nothing after it is handled. Both recipient keys are searched before anything is
+// handled so a transfer found only under the second key still settles the
+// order ahead of a later one.
+func (w *Worker) scanAddress(ctx context.Context, addr, denom string, from, to int64, handle func(chain.Tx, payments.Transfer) error) (int64, error) {
+	var txs []chain.Tx
+	seen := map[string]bool{}
 	for _, key := range []string{"transfer.recipient", "coin_received.receiver"} {
-		query := buildRecipientQuery(key, order.RecipientAddress)
+		query := buildRecipientQuery(key, addr, from, to)
 		page := 1
 		perPage := w.PerPage
 		if perPage <= 0 {
@@ -149,50 +422,105 @@ func (w *Worker) scanOrder(ctx context.Context, order *models.Order, from, to in
 		}
 
 		for {
-			res, err := w.Chain.TxSearch(ctx, query, page, perPage)
+			res, err := w.Chain.TxSearch(ctx, query, page, perPage, chain.OrderAsc)
 			if err != nil {
-				return err
+				return 0, err
 			}
 			if res.TotalCount == 0 {
 				break
 			}
 			for _, tx := range res.Txs {
-				if tx.Height < from || tx.Height > to {
-					continue
-				}
-				if tx.Code != 0 {
+				// Nodes that ignore the height bounds are filtered here.
+				if tx.Height < from || tx.Height > to || tx.Code != 0 || seen[tx.Hash] {
 					continue
 				}
-				for _, t := range payments.ExtractTransfers(tx.Events, order.Denom) {
-					if t.Recipient != order.RecipientAddress {
-						continue
-					}
-					if err := w.applyPayment(ctx, order, tx, t.Amount, t.Sender); err != nil {
-						log.Printf("apply payment failed order=%s tx=%s: %v", order.OrderID, tx.Hash, err)
-					}
-				}
+				seen[tx.Hash] = true
+				txs = append(txs, tx)
 			}
 
-			if int64(page*perPage) >= res.TotalCount {
+			if res.PerPage > 0 {
+				perPage = res.PerPage
+			}
+			if len(res.Txs) == 0 || int64(page*perPage) >= res.TotalCount {
 				break
 			}
 			page++
 		}
 	}
-	return nil
+	if len(txs) == 0 {
+		return 0, nil
+	}
+	sort.SliceStable(txs, func(i, j int) bool { return txs[i].Height < txs[j].Height })
+	if err := chain.FillBlockTimes(ctx, w.Chain, txs); err != nil {
+		log.Printf("block times failed addr=%s: %v", addr, err)
+	}
+
+	for _, tx := range txs {
+		for _, t := range payments.ExtractTransfers(tx.Events, denom) {
+			if t.Recipient != addr {
+				continue
+			}
+			// A later transfer must not settle the order ahead of this one.
+			if err := handle(tx, t); err != nil {
+				return tx.Height, nil
+			}
+		}
+	}
+	return 0, nil
 }
 
 func (w *Worker) applyPayment(ctx context.Context, order *models.Order, tx chain.Tx, amount string, sender string) error {
-	status, updated, err := payments.ApplyPayment(ctx, w.Store, order, tx, amount, sender)
+	val, err := w.Pricing.Valuate(ctx, order.PriceSnapshot, amount, w.Decimals)
+	if err != nil {
+		log.Printf("fiat valuation failed order=%s: %v", order.OrderID, err)
+	}
+	status, updated, err := payments.ApplyPayment(ctx, w.Store, order, tx, amount, sender, val, w.Verify)
 	if err != nil {
 		return err
 	}
 	if updated {
 		log.Printf("order %s -> %s tx=%s amount=%s", order.OrderID, status, tx.Hash, amount)
+		// Later transfers in the same scan are orphans of this order.
+		order.Status = status
+		order.TxHash = &tx.Hash
+	}
+	return nil
+}
+
+// handleTransfer settles a transfer to an unsettled order and records one to
+// an order already settled by another tx as an orphan payment.
+func (w *Worker) handleTransfer(ctx context.Context, order *models.Order, tx chain.Tx, amount string, sender string) error {
+	switch order.Status {
+	case models.OrderCreated, models.OrderExpired:
+		return w.applyPayment(ctx, order, tx, amount, sender)
+	}
+	if order.TxHash != nil && *order.TxHash == tx.Hash {
+		return nil
+	}
+	blockTime := tx.Timestamp
+	if blockTime.IsZero() {
+		blockTime = time.Now().UTC()
+	}
+	added, err := w.Store.InsertOrphanPayment(ctx, &models.OrphanPayment{
+		TxHash:      tx.Hash,
+		OrderID:     order.OrderID,
+		FromAddress: sender,
+		ToAddress:   order.RecipientAddress,
+		AmountPeaka: amount,
+		Denom:       order.Denom,
+		Height:      tx.Height,
+		BlockTime:   blockTime,
+	})
+	if err != nil {
+		return err
+	}
+	if added {
+		log.Printf("orphan payment to settled order %s tx=%s amount=%s", order.OrderID, tx.Hash, amount)
 	}
 	return nil
 }
 
-func buildRecipientQuery(key, addr string) string {
-	return key + "='" + addr + "'"
+func buildRecipientQuery(key, addr string, from, to int64) string {
+	return key + "='" + addr + "' AND tx.height>=" + strconv.FormatInt(from, 10) +
+		" AND tx.height<=" + strconv.FormatInt(to, 10)
 }
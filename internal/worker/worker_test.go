@@ -0,0 +1,211 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"DORAPollCredit/internal/chain"
+	"DORAPollCredit/internal/payments"
+)
+
+// fakeChain answers transfer.recipient searches with the txs inside the
+// query's height bounds, sorted as requested.
+type fakeChain struct {
+	txs    []chain.Tx
+	latest int64
+}
+
+func (f *fakeChain) LatestHeight(ctx context.Context) (int64, error) { return f.latest, nil }
+
+func (f *fakeChain) TxSearch(ctx context.Context, query string, page, perPage int, order chain.TxSearchOrder) (*chain.TxSearchResult, error) {
+	if !strings.HasPrefix(query, "transfer.recipient=") {
+		return &chain.TxSearchResult{}, nil
+	}
+	var from, to int64
+	for _, term := range strings.Split(query, " AND ") {
+		if v, ok := strings.CutPrefix(term, "tx.height>="); ok {
+			from, _ = strconv.ParseInt(v, 10, 64)
+		}
+		if v, ok := strings.CutPrefix(term, "tx.height<="); ok {
+			to, _ = strconv.ParseInt(v, 10, 64)
+		}
+	}
+	var txs []chain.Tx
+	for _, tx := range f.txs {
+		if tx.Height >= from && tx.Height <= to {
+			txs = append(txs, tx)
+		}
+	}
+	sort.SliceStable(txs, func(i, j int) bool {
+		if order == chain.OrderDesc {
+			return txs[i].Height > txs[j].Height
+		}
+		return txs[i].Height < txs[j].Height
+	})
+	return &chain.TxSearchResult{TotalCount: int64(len(txs)), PerPage: perPage, Txs: txs}, nil
+}
+
+func (f *fakeChain) TxByHash(ctx context.Context, hash string) (*chain.Tx, error) {
+	return nil, errors.New("not found")
+}
+
+func (f *fakeChain) BlockTime(ctx context.Context, height int64) (time.Time, error) {
+	return time.Unix(height, 0), nil
+}
+
+func (f *fakeChain) BlockTimes(ctx context.Context, heights []int64) (map[int64]time.Time, error) {
+	out := make(map[int64]time.Time, len(heights))
+	for _, h := range heights {
+		out[h] = time.Unix(h, 0)
+	}
+	return out, nil
+}
+
+func (f *fakeChain) Block(ctx context.Context, height int64) (*chain.Block, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeChain) BlockResults(ctx context.Context, height int64) (*chain.BlockResults, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeChain) BaseURL() string { return "fake" }
+
+func transferTx(hash string, height int64, to, amount string) chain.Tx {
+	return chain.Tx{
+		Hash:   hash,
+		Height: height,
+		Events: []chain.Event{{
+			Type: "transfer",
+			Attributes: []chain.Attribute{
+				{Key: "recipient", Value: to},
+				{Key: "sender", Value: "dora1sender"},
+				{Key: "amount", Value: amount},
+			},
+		}},
+	}
+}
+
+func TestScanAddressRetriesFailedPayment(t *testing.T) {
+	const addr = "dora1order"
+	w := &Worker{Chain: &fakeChain{txs: []chain.Tx{
+		transferTx("B", 107, addr, "5peaka"),
+		transferTx("A", 105, addr, "10peaka"),
+	}}}
+	ctx := context.Background()
+
+	attempts := map[string]int{}
+	applied := map[string]bool{}
+	handle := func(tx chain.Tx, tr payments.Transfer) error {
+		attempts[tx.Hash]++
+		if tx.Hash == "A" && attempts[tx.Hash] == 1 {
+			return errors.New("database unavailable")
+		}
+		applied[tx.Hash] = true
+		return nil
+	}
+
+	failed, err := w.scanAddress(ctx, addr, "peaka", 100, 110, handle)
+	if err != nil {
+		t.Fatalf("first scan: %v", err)
+	}
+	if failed != 105 {
+		t.Fatalf("failed height = %d, want 105", failed)
+	}
+	cursor := scanLimit(110, failed)
+	if cursor != 104 {
+		t.Fatalf("cursor = %d, want 104", cursor)
+	}
+	if applied["A"] || applied["B"] {
+		t.Fatalf("applied %v despite the failure at 105", applied)
+	}
+
+	failed, err = w.scanAddress(ctx, addr, "peaka", cursor+1, 110, handle)
+	if err != nil {
+		t.Fatalf("second scan: %v", err)
+	}
+	if failed != 0 {
+		t.Fatalf("failed height = %d after retry, want 0", failed)
+	}
+	if !applied["A"] || !applied["B"] {
+		t.Fatalf("applied %v on the next scan, want A and B", applied)
+	}
+	if got := scanLimit(110, failed); got != 110 {
+		t.Fatalf("cursor = %d, want 110", got)
+	}
+}
+
+func TestScanAddressOldestTransferFirst(t *testing.T) {
+	const addr = "dora1order"
+	w := &Worker{Chain: &fakeChain{txs: []chain.Tx{
+		transferTx("newer", 108, addr, "7peaka"),
+		transferTx("older", 103, addr, "10peaka"),
+		transferTx("outside", 99, addr, "1peaka"),
+	}}}
+
+	// Like handleTransfer: the first transfer settles, the rest are orphans.
+	var settledBy string
+	var orphans []string
+	handle := func(tx chain.Tx, tr payments.Transfer) error {
+		if settledBy == "" {
+			settledBy = tx.Hash
+			return nil
+		}
+		orphans = append(orphans, tx.Hash)
+		return nil
+	}
+	failed, err := w.scanAddress(context.Background(), addr, "peaka", 100, 110, handle)
+	if err != nil || failed != 0 {
+		t.Fatalf("scan = %d, %v", failed, err)
+	}
+	if settledBy != "older" {
+		t.Errorf("settled by %q, want the older transfer", settledBy)
+	}
+	if len(orphans) != 1 || orphans[0] != "newer" {
+		t.Errorf("orphans = %v, want [newer]", orphans)
+	}
+}
+
+func TestScanLimit(t *testing.T) {
+	tests := []struct {
+		to, failed, want int64
+	}{
+		{to: 110, failed: 0, want: 110},
+		{to: 110, failed: 105, want: 104},
+		{to: 110, failed: 110, want: 109},
+		{to: 110, failed: 100, want: 99},
+	}
+	for _, tt := range tests {
+		if got := scanLimit(tt.to, tt.failed); got != tt.want {
+			t.Errorf("scanLimit(%d, %d) = %d, want %d", tt.to, tt.failed, got, tt.want)
+		}
+	}
+}
+
+func TestCursorStart(t *testing.T) {
+	h := func(v int64) *int64 { return &v }
+	tests := []struct {
+		name             string
+		scanned, created *int64
+		synced           int64
+		want             int64
+	}{
+		{name: "cursor", scanned: h(11450900), created: h(11450800), synced: 11451000, want: 11450901},
+		{name: "created height", created: h(11450800), synced: 11451000, want: 11450800},
+		{name: "no created height", synced: 11451000, want: 11451000 - syncMargin},
+		{name: "no created height near genesis", synced: 40, want: 1},
+		{name: "never synced", want: 11450000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cursorStart(tt.scanned, tt.created, tt.synced, 11450000); got != tt.want {
+				t.Errorf("cursorStart = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
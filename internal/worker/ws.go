@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"log"
+	"sort"
 	"time"
 
 	"DORAPollCredit/internal/chain"
@@ -12,6 +13,36 @@ import (
 	"github.com/jackc/pgx/v5"
 )
 
+const (
+	// wsDedupTTL is how long a tx hash is remembered; late copies from slow
+	// endpoints arriving after that are applied again, which is idempotent.
+	wsDedupTTL       = 10 * time.Minute
+	wsStatsInterval  = 5 * time.Minute
+	wsEventQueueSize = 256
+	// defaultWSBackfillBlocks is how far back to backfill when no stream
+	// height was ever recorded.
+	defaultWSBackfillBlocks = 200
+)
+
+// wsEvent is one Tx event as delivered by one endpoint.
+type wsEvent struct {
+	endpoint string
+	tx       *chain.Tx
+	at       time.Time
+}
+
+// wsEndpointStats tracks how each endpoint compares with the first delivery
+// of the same tx.
+type wsEndpointStats struct {
+	delivered int64
+	first     int64
+	totalLag  time.Duration
+	maxLag    time.Duration
+}
+
+// RunWS subscribes on up to WSRedundancy endpoints at once and merges their
+// Tx events, applying each tx hash once. Each connection subscribes only to
+// transfers to watched addresses while they fit in WSMaxSubscriptions.
 func (w *Worker) RunWS(ctx context.Context) {
 	if len(w.WSEndpoints) == 0 {
 		log.Printf("ws disabled: ws_endpoints is empty")
@@ -20,10 +51,33 @@ func (w *Worker) RunWS(ctx context.Context) {
 
 	backfillBlocks := w.WSBackfillBlocks
 	if backfillBlocks <= 0 {
-		backfillBlocks = w.RewindBlocks
+		backfillBlocks = defaultWSBackfillBlocks
+	}
+	if high, err := w.Store.GetWSHeight(ctx); err != nil {
+		log.Printf("load ws height failed: %v", err)
+	} else {
+		w.wsHigh.Store(high)
 	}
-	if backfillBlocks < 0 {
-		backfillBlocks = 0
+
+	slots := w.WSRedundancy
+	if slots <= 0 || slots > len(w.WSEndpoints) {
+		slots = len(w.WSEndpoints)
+	}
+
+	events := make(chan wsEvent, wsEventQueueSize)
+	for slot := 0; slot < slots; slot++ {
+		go w.runWSSlot(ctx, slot, slots, backfillBlocks, events)
+	}
+	w.mergeWS(ctx, events)
+}
+
+// runWSSlot keeps one subscription alive. Slot i uses endpoints i, i+stride,
+// ... and moves to the next one after repeated failures, so concurrent slots
+// never share an endpoint.
+func (w *Worker) runWSSlot(ctx context.Context, slot, stride int, backfillBlocks int64, out chan<- wsEvent) {
+	var endpoints []string
+	for i := slot; i < len(w.WSEndpoints); i += stride {
+		endpoints = append(endpoints, w.WSEndpoints[i])
 	}
 
 	failoverThreshold := w.WSFailoverThreshold
@@ -36,6 +90,15 @@ func (w *Worker) RunWS(ctx context.Context) {
 	backoff := 2 * time.Second
 	maxBackoff := 30 * time.Second
 
+	fail := func() {
+		failCount++
+		if failCount >= failoverThreshold && len(endpoints) > 1 {
+			index = (index + 1) % len(endpoints)
+			failCount = 0
+			log.Printf("ws failover -> %s", endpoints[index])
+		}
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -43,17 +106,21 @@ func (w *Worker) RunWS(ctx context.Context) {
 		default:
 		}
 
-		endpoint := w.WSEndpoints[index]
+		endpoint := endpoints[index]
 		client := chain.NewWSClient(endpoint)
+		client.Encoding = w.AttrEncoding
+		if w.WSPingInterval > 0 {
+			client.PingInterval = w.WSPingInterval
+		}
+		if w.WSReadTimeout > 0 {
+			client.ReadTimeout = w.WSReadTimeout
+		}
 		if err := client.Connect(ctx); err != nil {
 			log.Printf("ws connect failed (%s): %v", endpoint, err)
-			failCount++
-			if failCount >= failoverThreshold && len(w.WSEndpoints) > 1 {
-				index = (index + 1) % len(w.WSEndpoints)
-				failCount = 0
-				log.Printf("ws failover -> %s", w.WSEndpoints[index])
+			fail()
+			if !sleepCtx(ctx, backoff) {
+				return
 			}
-			time.Sleep(backoff)
 			if backoff < maxBackoff {
 				backoff *= 2
 				if backoff > maxBackoff {
@@ -66,62 +133,59 @@ func (w *Worker) RunWS(ctx context.Context) {
 		backoff = 2 * time.Second
 		failCount = 0
 
-		if err := client.Subscribe(ctx, "tm.event='Tx'"); err != nil {
-			log.Printf("ws subscribe failed (%s): %v", endpoint, err)
-			client.Close()
-			failCount++
-			if failCount >= failoverThreshold && len(w.WSEndpoints) > 1 {
-				index = (index + 1) % len(w.WSEndpoints)
-				failCount = 0
-				log.Printf("ws failover -> %s", w.WSEndpoints[index])
-			}
-			time.Sleep(backoff)
-			continue
-		}
+		connCtx, cancel := context.WithCancel(ctx)
+		subs := newWSSubscriptions(client, endpoint, w.WSMaxSubscriptions)
+		go subs.run(connCtx, w, w.WSSubscriptionSync)
 
-		if backfillBlocks > 0 {
-			w.BackfillRecent(ctx, backfillBlocks)
-		}
+		w.requestBackfill(ctx, backfillBlocks)
 
 		for {
 			msg, err := client.Read(ctx)
 			if err != nil {
-				log.Printf("ws read failed (%s): %v", endpoint, err)
+				cancel()
 				client.Close()
-				failCount++
-				if failCount >= failoverThreshold && len(w.WSEndpoints) > 1 {
-					index = (index + 1) % len(w.WSEndpoints)
-					failCount = 0
-					log.Printf("ws failover -> %s", w.WSEndpoints[index])
+				if ctx.Err() != nil {
+					return
 				}
+				var stale *chain.StaleConnectionError
+				if errors.As(err, &stale) {
+					log.Printf("ws stale, reconnecting: %v", err)
+				} else {
+					log.Printf("ws read failed (%s): %v", endpoint, err)
+				}
+				fail()
 				break
 			}
 
-			tx, ok, err := chain.ParseWSTx(msg)
+			if block, ok, _ := chain.ParseWSNewBlock(msg); ok {
+				w.notifyHead(block.Height)
+				continue
+			}
+
+			tx, ok, err := chain.ParseWSTx(msg, client.Encoding)
+			if chain.IsSubscriptionLimitError(err) {
+				subs.limitReached()
+				continue
+			}
 			if err != nil {
-				log.Printf("ws parse failed: %v", err)
+				log.Printf("ws parse failed (%s): %v", endpoint, err)
 				continue
 			}
 			if !ok || tx.Code != 0 || tx.Hash == "" {
 				continue
 			}
-
-			for _, t := range payments.ExtractTransfers(tx.Events, w.Denom) {
-				order, err := w.Store.GetPendingOrderByRecipient(ctx, t.Recipient)
-				if err != nil {
-					if errors.Is(err, pgx.ErrNoRows) {
-						continue
-					}
-					log.Printf("ws get order failed: %v", err)
-					continue
-				}
-				if err := w.applyPayment(ctx, order, *tx, t.Amount, t.Sender); err != nil {
-					log.Printf("ws apply payment failed: %v", err)
-				}
+			select {
+			case out <- wsEvent{endpoint: endpoint, tx: tx, at: time.Now()}:
+			case <-ctx.Done():
+				cancel()
+				client.Close()
+				return
 			}
 		}
 
-		time.Sleep(backoff)
+		if !sleepCtx(ctx, backoff) {
+			return
+		}
 		if backoff < maxBackoff {
 			backoff *= 2
 			if backoff > maxBackoff {
@@ -130,3 +194,115 @@ func (w *Worker) RunWS(ctx context.Context) {
 		}
 	}
 }
+
+// mergeWS applies the first copy of each tx and records how far behind the
+// other endpoints delivered it.
+func (w *Worker) mergeWS(ctx context.Context, events <-chan wsEvent) {
+	seen := make(map[string]time.Time)
+	stats := make(map[string]*wsEndpointStats)
+	ticker := time.NewTicker(wsStatsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			logWSStats(stats)
+			for hash, at := range seen {
+				if now.Sub(at) > wsDedupTTL {
+					delete(seen, hash)
+				}
+			}
+		case ev := <-events:
+			st := stats[ev.endpoint]
+			if st == nil {
+				st = &wsEndpointStats{}
+				stats[ev.endpoint] = st
+			}
+			st.delivered++
+			if first, ok := seen[ev.tx.Hash]; ok {
+				lag := ev.at.Sub(first)
+				st.totalLag += lag
+				st.maxLag = max(st.maxLag, lag)
+				continue
+			}
+			seen[ev.tx.Hash] = ev.at
+			st.first++
+			w.handleWSTx(ctx, ev.tx)
+		}
+	}
+}
+
+// notifyHead raises the WS high-water mark and passes the height to Run
+// without blocking the reader; Run only needs the newest one.
+func (w *Worker) notifyHead(height int64) {
+	for {
+		high := w.wsHigh.Load()
+		if height <= high || w.wsHigh.CompareAndSwap(high, height) {
+			break
+		}
+	}
+	if w.heads == nil {
+		return
+	}
+	select {
+	case w.heads <- height:
+	default:
+	}
+}
+
+// handleWSTx records transfers to watched orders, and to other derived
+// addresses, as seen. finalizeSeen settles or records them as unmatched once
+// confirm_depth blocks deep, exactly like polled payments.
+func (w *Worker) handleWSTx(ctx context.Context, tx *chain.Tx) {
+	for _, t := range payments.ExtractTransfers(tx.Events, w.Denom) {
+		order, err := w.Store.GetWatchedOrderByRecipient(ctx, t.Recipient, w.expiredSince(), w.settledSince())
+		if err != nil {
+			if !errors.Is(err, pgx.ErrNoRows) {
+				log.Printf("ws get order failed: %v", err)
+				continue
+			}
+			if _, ok := w.registry.lookup(t.Recipient); !ok {
+				continue
+			}
+			if err := w.Store.InsertSeenPayment(ctx, tx.Hash, "", t.Recipient, tx.Height); err != nil {
+				log.Printf("ws record seen unmatched payment failed: %v", err)
+			}
+			continue
+		}
+		if err := w.Store.InsertSeenPayment(ctx, tx.Hash, order.OrderID, order.RecipientAddress, tx.Height); err != nil {
+			log.Printf("ws record seen payment failed: %v", err)
+			continue
+		}
+		log.Printf("order %s seen tx=%s height=%d amount=%s", order.OrderID, tx.Hash, tx.Height, t.Amount)
+	}
+}
+
+func logWSStats(stats map[string]*wsEndpointStats) {
+	endpoints := make([]string, 0, len(stats))
+	for ep := range stats {
+		endpoints = append(endpoints, ep)
+	}
+	sort.Strings(endpoints)
+	for _, ep := range endpoints {
+		st := stats[ep]
+		var avg time.Duration
+		if late := st.delivered - st.first; late > 0 {
+			avg = st.totalLag / time.Duration(late)
+		}
+		log.Printf("ws stats %s delivered=%d first=%d avg_lag=%s max_lag=%s", ep, st.delivered, st.first, avg, st.maxLag)
+	}
+}
+
+// sleepCtx waits for d and reports false if ctx ended first.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS products (
+  product_id TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  credit_amount BIGINT NOT NULL,
+  bonus_credit BIGINT NOT NULL DEFAULT 0,
+  price_peaka TEXT,
+  active BOOLEAN NOT NULL DEFAULT true,
+  active_from TIMESTAMPTZ,
+  active_until TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS product_id TEXT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS quantity BIGINT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS product_snapshot JSONB;
+
+CREATE INDEX IF NOT EXISTS orders_product_id_idx ON orders (product_id);
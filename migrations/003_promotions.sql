@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS promo_codes (
+  code TEXT PRIMARY KEY,
+  kind TEXT NOT NULL,
+  discount_bps BIGINT NOT NULL DEFAULT 0,
+  bonus_credit BIGINT NOT NULL DEFAULT 0,
+  max_redemptions BIGINT,
+  per_user_limit BIGINT,
+  redeemed_count BIGINT NOT NULL DEFAULT 0,
+  active BOOLEAN NOT NULL DEFAULT true,
+  expires_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS promo_redemptions (
+  order_id TEXT PRIMARY KEY REFERENCES orders(order_id),
+  code TEXT NOT NULL REFERENCES promo_codes(code),
+  user_id TEXT NOT NULL,
+  redeemed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS promo_redemptions_code_user_idx ON promo_redemptions (code, user_id);
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS promo_code TEXT;
@@ -0,0 +1,5 @@
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS fiat_currency TEXT;
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS fiat_rate TEXT;
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS fiat_amount NUMERIC;
+
+CREATE INDEX IF NOT EXISTS payments_block_time_idx ON payments (block_time);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS order_quotes (
+  id BIGSERIAL PRIMARY KEY,
+  order_id TEXT NOT NULL REFERENCES orders(order_id),
+  amount_peaka TEXT NOT NULL,
+  price_snapshot JSONB NOT NULL,
+  expires_at TIMESTAMPTZ NOT NULL,
+  replaced_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS order_quotes_order_id_idx ON order_quotes (order_id);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS seen_payments (
+  tx_hash TEXT NOT NULL,
+  order_id TEXT NOT NULL REFERENCES orders(order_id),
+  height BIGINT NOT NULL,
+  seen_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (tx_hash, order_id)
+);
+
+CREATE INDEX IF NOT EXISTS seen_payments_height_idx ON seen_payments (height);
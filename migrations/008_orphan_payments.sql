@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS orphan_payments (
+  tx_hash TEXT PRIMARY KEY,
+  order_id TEXT NOT NULL REFERENCES orders(order_id),
+  from_address TEXT,
+  to_address TEXT NOT NULL,
+  amount_peaka TEXT NOT NULL,
+  denom TEXT NOT NULL,
+  height BIGINT NOT NULL,
+  block_time TIMESTAMPTZ NOT NULL,
+  status TEXT NOT NULL DEFAULT 'open',
+  credit_issued BIGINT,
+  refund_tx_hash TEXT,
+  note TEXT,
+  resolved_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS orphan_payments_order_id_idx ON orphan_payments (order_id);
+CREATE INDEX IF NOT EXISTS orphan_payments_status_idx ON orphan_payments (status);
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS unmatched_payments (
+  id BIGSERIAL PRIMARY KEY,
+  tx_hash TEXT NOT NULL,
+  to_address TEXT NOT NULL,
+  derivation_index BIGINT NOT NULL,
+  order_id TEXT REFERENCES orders(order_id),
+  from_address TEXT,
+  amount_peaka TEXT NOT NULL,
+  denom TEXT NOT NULL,
+  height BIGINT NOT NULL,
+  block_time TIMESTAMPTZ NOT NULL,
+  reason TEXT NOT NULL,
+  status TEXT NOT NULL DEFAULT 'open',
+  resolved_order_id TEXT REFERENCES orders(order_id),
+  note TEXT,
+  resolved_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  UNIQUE (tx_hash, to_address)
+);
+
+CREATE INDEX IF NOT EXISTS unmatched_payments_status_idx ON unmatched_payments (status);
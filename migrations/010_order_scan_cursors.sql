@@ -0,0 +1,10 @@
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS created_height BIGINT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS scanned_height BIGINT;
+
+-- Existing orders were covered by the global cursors up to the lower of them.
+UPDATE orders
+SET scanned_height = (
+  SELECT MIN(value::bigint) FROM sync_state
+  WHERE key IN ('last_processed_height', 'late_scan_height')
+)
+WHERE scanned_height IS NULL;
@@ -0,0 +1,13 @@
+-- Transfers to derived addresses no watched order takes are also held here
+-- until confirmed, keyed by recipient; order_id is NULL for them.
+ALTER TABLE seen_payments ADD COLUMN IF NOT EXISTS to_address TEXT;
+
+UPDATE seen_payments s
+SET to_address = o.recipient_address
+FROM orders o
+WHERE s.order_id = o.order_id AND s.to_address IS NULL;
+
+ALTER TABLE seen_payments ALTER COLUMN to_address SET NOT NULL;
+ALTER TABLE seen_payments ALTER COLUMN order_id DROP NOT NULL;
+ALTER TABLE seen_payments DROP CONSTRAINT IF EXISTS seen_payments_pkey;
+ALTER TABLE seen_payments ADD PRIMARY KEY (tx_hash, to_address);